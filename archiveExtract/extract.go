@@ -0,0 +1,274 @@
+package archiveExtract
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"wget/exitStatus"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Limits on what one archive may unpack, so a hostile or broken archive
+// (a zip bomb, or millions of empty files) can't fill the disk or the
+// inode table. They are far above what real software releases and data
+// exports need. An archive that goes over either limit stops extracting
+// with an error; the entries written until then are kept.
+const (
+	// MaxExtractedEntries is the largest number of files, directories and
+	// links one archive may create
+	MaxExtractedEntries = 100000
+	// MaxExtractedBytes is the largest total size of the files one archive
+	// may write, 8 GiB
+	MaxExtractedBytes = 8 << 30
+)
+
+// Magic bytes of the supported formats
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic  = []byte("PK\x03\x04")
+	// zipEmpty starts a zip archive with no entries
+	zipEmpty = []byte("PK\x05\x06")
+)
+
+// Start unpacks the tar, tar.gz, tar.xz, tar.zst or zip archive in file
+// into dir (--extract). The format is recognized by its content, not its
+// name. Files that aren't archives are left alone with a warning.
+func Start(file, dir string, logger *slog.Logger) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, err)
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, err)
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, err)
+	}
+	e := &extractor{dir: root}
+
+	buffered := bufio.NewReader(f)
+	head, _ := buffered.Peek(512)
+	var format string
+	switch {
+	case bytes.HasPrefix(head, zipMagic) || bytes.HasPrefix(head, zipEmpty):
+		format = "zip"
+		info, statErr := f.Stat()
+		if err = statErr; err == nil {
+			err = e.zip(f, info.Size())
+		}
+	case bytes.HasPrefix(head, gzipMagic):
+		format = "tar.gz"
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(buffered); err == nil {
+			err = e.compressedTar(gz)
+		}
+	case bytes.HasPrefix(head, xzMagic):
+		format = "tar.xz"
+		var xr *xz.Reader
+		if xr, err = xz.NewReader(buffered); err == nil {
+			err = e.compressedTar(xr)
+		}
+	case bytes.HasPrefix(head, zstdMagic):
+		format = "tar.zst"
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(buffered); err == nil {
+			err = e.compressedTar(zr)
+			zr.Close()
+		}
+	case isTar(head):
+		format = "tar"
+		err = e.tar(buffered)
+	default:
+		err = errNotArchive
+	}
+
+	if errors.Is(err, errNotArchive) {
+		logger.Warn("Not a tar or zip archive, nothing extracted", "path", file)
+		return nil
+	}
+	if err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("cannot extract %s: %w", file, err))
+	}
+	logger.Info("Archive extracted", "path", file, "format", format, "dir", dir, "entries", e.entries, "bytes", e.size)
+	return nil
+}
+
+// errNotArchive means the data is not in a format we unpack
+var errNotArchive = errors.New("not an archive")
+
+// isTar reports whether block is the header of a POSIX or GNU tar archive
+func isTar(block []byte) bool {
+	return len(block) >= 262 && bytes.Equal(block[257:262], []byte("ustar"))
+}
+
+// compressedTar unpacks r if it holds a tar archive
+func (e *extractor) compressedTar(r io.Reader) error {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(512)
+	if !isTar(head) {
+		return errNotArchive
+	}
+	return e.tar(buffered)
+}
+
+// extractor writes archive entries below dir. Entry names are checked
+// before anything is written, so nothing lands outside dir through "..",
+// absolute names or symbolic links.
+type extractor struct {
+	// dir is the destination with its symbolic links resolved
+	dir     string
+	entries int
+	size    int64
+}
+
+// target returns where the entry called name goes, after checking that it
+// stays inside the destination. The parent directories are created.
+func (e *extractor) target(name string) (string, error) {
+	e.entries++
+	if e.entries > MaxExtractedEntries {
+		return "", fmt.Errorf("more than %d entries", MaxExtractedEntries)
+	}
+
+	// Some zip tools write Windows separators
+	local := filepath.FromSlash(strings.ReplaceAll(name, `\`, "/"))
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("unsafe path %q in archive", name)
+	}
+	path := filepath.Join(e.dir, local)
+
+	// A symbolic link already in place could lead the directories we
+	// create elsewhere, so check the deepest one that exists first
+	existing := filepath.Dir(path)
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	if err := e.inside(existing, name); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	return path, e.inside(filepath.Dir(path), name)
+}
+
+// inside checks that path, with symbolic links resolved, is within dir
+func (e *extractor) inside(path, name string) error {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(e.dir, real)
+	if err != nil || !(rel == "." || filepath.IsLocal(rel)) {
+		return fmt.Errorf("%q in archive leads outside the destination through a symbolic link", name)
+	}
+	return nil
+}
+
+// file writes a regular file, never following a link left at its path
+func (e *extractor) file(name string, mode fs.FileMode, r io.Reader) error {
+	path, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if err := remove(path); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, io.LimitReader(r, MaxExtractedBytes-e.size+1))
+	e.size += n
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && e.size > MaxExtractedBytes {
+		err = fmt.Errorf("more than %d bytes of content", int64(MaxExtractedBytes))
+	}
+	return err
+}
+
+// mkdir creates a directory entry
+func (e *extractor) mkdir(name string) error {
+	path, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	return e.inside(path, name)
+}
+
+// symlink creates a symbolic link whose target must stay inside the
+// destination, both as written and once resolved
+func (e *extractor) symlink(name, target string) error {
+	rel := filepath.Join(filepath.Dir(filepath.FromSlash(name)), filepath.FromSlash(target))
+	if filepath.IsAbs(filepath.FromSlash(target)) || !filepath.IsLocal(rel) {
+		return fmt.Errorf("symbolic link %q in archive points outside the destination (%s)", name, target)
+	}
+	path, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if err := remove(path); err != nil {
+		return err
+	}
+	if err := os.Symlink(target, path); err != nil {
+		return err
+	}
+	// Links through other links can still climb out; dangling ones can't
+	if _, err := os.Stat(path); err == nil {
+		if err := e.inside(path, name); err != nil {
+			os.Remove(path)
+			return err
+		}
+	}
+	return nil
+}
+
+// link creates a hard link to an entry extracted earlier
+func (e *extractor) link(name, target string) error {
+	local := filepath.FromSlash(target)
+	if !filepath.IsLocal(local) {
+		return fmt.Errorf("hard link %q in archive points outside the destination (%s)", name, target)
+	}
+	source := filepath.Join(e.dir, local)
+	if err := e.inside(filepath.Dir(source), name); err != nil {
+		return err
+	}
+	path, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if err := remove(path); err != nil {
+		return err
+	}
+	return os.Link(source, path)
+}
+
+// remove deletes whatever is at path so it can be replaced
+func remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
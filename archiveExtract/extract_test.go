@@ -0,0 +1,261 @@
+package archiveExtract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// entry is one member of a test archive
+type entry struct {
+	name string
+	// kind is 'f' for a file, 'd' for a directory, 's' for a symbolic link
+	// and 'h' for a hard link
+	kind byte
+	// body is the content of a file or the target of a link
+	body string
+}
+
+func makeTar(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644}
+		switch e.kind {
+		case 'f':
+			header.Typeflag, header.Size = tar.TypeReg, int64(len(e.body))
+		case 'd':
+			header.Typeflag, header.Mode = tar.TypeDir, 0755
+		case 's':
+			header.Typeflag, header.Linkname = tar.TypeSymlink, e.body
+		case 'h':
+			header.Typeflag, header.Linkname = tar.TypeLink, e.body
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if e.kind == 'f' {
+			io.WriteString(tw, e.body)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeZip(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		switch e.kind {
+		case 'f':
+			header.SetMode(0644)
+		case 'd':
+			header.Name += "/"
+			header.SetMode(fs.ModeDir | 0755)
+		case 's':
+			header.SetMode(fs.ModeSymlink | 0777)
+		default:
+			t.Fatalf("zip archives have no %c entries", e.kind)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, e.body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		zip     bool
+		entries []entry
+		// setup prepares the destination before extracting
+		setup func(t *testing.T, dest, outside string)
+		// files are the contents expected in the destination afterwards
+		files   map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "tar",
+			entries: []entry{{"docs", 'd', ""}, {"docs/a.txt", 'f', "a"}, {"latest", 's', "docs/a.txt"}, {"copy.txt", 'h', "docs/a.txt"}},
+			files:   map[string]string{"docs/a.txt": "a", "latest": "a", "copy.txt": "a"},
+		},
+		{
+			name:    "zip",
+			zip:     true,
+			entries: []entry{{"docs", 'd', ""}, {"docs/a.txt", 'f', "a"}, {"latest", 's', "docs/a.txt"}},
+			files:   map[string]string{"docs/a.txt": "a", "latest": "a"},
+		},
+		{
+			name:    "tar dot-dot",
+			entries: []entry{{"../outside/evil", 'f', "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "zip dot-dot",
+			zip:     true,
+			entries: []entry{{"docs/../../outside/evil", 'f', "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "zip backslash dot-dot",
+			zip:     true,
+			entries: []entry{{`..\outside\evil`, 'f', "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "tar absolute path",
+			entries: []entry{{"/tmp/evil", 'f', "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "zip absolute path",
+			zip:     true,
+			entries: []entry{{"/tmp/evil", 'f', "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "tar symlink outside, then a file through it",
+			entries: []entry{{"escape", 's', "../outside"}, {"escape/evil", 'f', "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "tar absolute symlink, then a file through it",
+			entries: []entry{{"escape", 's', "/"}, {"escape/tmp/evil", 'f', "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "zip symlink outside, then a file through it",
+			zip:     true,
+			entries: []entry{{"escape", 's', "../outside"}, {"escape/evil", 'f', "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "tar symlink chain leading outside",
+			entries: []entry{{"a", 'd', ""}, {"a/up", 's', ".."}, {"b", 's', "a/up/a/up/.."}, {"b/evil", 'f', "x"}},
+			wantErr: true,
+		},
+		{
+			name: "symlink already in the destination",
+			setup: func(t *testing.T, dest, outside string) {
+				if err := os.Symlink(outside, filepath.Join(dest, "escape")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			entries: []entry{{"escape/evil", 'f', "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "tar hard link outside",
+			entries: []entry{{"stolen", 'h', "../outside/secret"}},
+			wantErr: true,
+		},
+		{
+			name:    "tar hard link through a symlink outside",
+			entries: []entry{{"up", 's', ".."}, {"stolen", 'h', "up/outside/secret"}},
+			wantErr: true,
+		},
+		{
+			name: "existing file is replaced",
+			setup: func(t *testing.T, dest, outside string) {
+				os.WriteFile(filepath.Join(dest, "a.txt"), []byte("old"), 0644)
+			},
+			entries: []entry{{"a.txt", 'f', "new"}},
+			files:   map[string]string{"a.txt": "new"},
+		},
+		{
+			name: "existing symlink is replaced, not written through",
+			setup: func(t *testing.T, dest, outside string) {
+				if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dest, "a.txt")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			entries: []entry{{"a.txt", 'f', "new"}},
+			files:   map[string]string{"a.txt": "new"},
+		},
+		{
+			name:    "entry repeated in the archive",
+			entries: []entry{{"a.txt", 'f', "first"}, {"a.txt", 's', "b.txt"}, {"b.txt", 'f', "b"}, {"a.txt", 'f', "last"}},
+			files:   map[string]string{"a.txt": "last", "b.txt": "b"},
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dest, outside := filepath.Join(dir, "dest"), filepath.Join(dir, "outside")
+			for _, d := range []string{dest, outside} {
+				if err := os.Mkdir(d, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			secret := filepath.Join(outside, "secret")
+			os.WriteFile(secret, []byte("secret"), 0644)
+			if tt.setup != nil {
+				tt.setup(t, dest, outside)
+			}
+
+			data := makeTar(t, tt.entries)
+			if tt.zip {
+				data = makeZip(t, tt.entries)
+			}
+			archive := filepath.Join(dir, "archive")
+			if err := os.WriteFile(archive, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Start(archive, dest, logger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Start() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			// Nothing may change outside the destination
+			if names, _ := os.ReadDir(outside); len(names) != 1 {
+				t.Errorf("files outside the destination: %v", names)
+			}
+			if content, _ := os.ReadFile(secret); string(content) != "secret" {
+				t.Errorf("file outside the destination was changed to %q", content)
+			}
+			if _, err := os.Lstat("/tmp/evil"); err == nil {
+				t.Error("file written to /tmp/evil")
+			}
+			for name, want := range tt.files {
+				got, err := os.ReadFile(filepath.Join(dest, name))
+				if err != nil || string(got) != want {
+					t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStartNotArchive(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "page.html")
+	os.WriteFile(file, []byte("<html></html>"), 0644)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if err := Start(file, filepath.Join(dir, "out"), logger); err != nil {
+		t.Fatalf("Start() = %v, want a warning only", err)
+	}
+	if names, _ := os.ReadDir(filepath.Join(dir, "out")); len(names) != 0 {
+		t.Errorf("extracted %v from a non-archive", names)
+	}
+}
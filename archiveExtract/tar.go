@@ -0,0 +1,35 @@
+package archiveExtract
+
+import (
+	"archive/tar"
+	"io"
+)
+
+// tar unpacks a tar stream. Devices, FIFOs and other special entries are
+// skipped.
+func (e *extractor) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeReg:
+			err = e.file(header.Name, header.FileInfo().Mode(), tr)
+		case tar.TypeDir:
+			err = e.mkdir(header.Name)
+		case tar.TypeSymlink:
+			err = e.symlink(header.Name, header.Linkname)
+		case tar.TypeLink:
+			err = e.link(header.Name, header.Linkname)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
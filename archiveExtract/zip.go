@@ -0,0 +1,54 @@
+package archiveExtract
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// zip unpacks a zip archive of the given size
+func (e *extractor) zip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, entry := range zr.File {
+		if err := e.zipEntry(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipEntry unpacks one zip entry. Symbolic links are stored with their
+// target as the content.
+func (e *extractor) zipEntry(entry *zip.File) error {
+	mode := entry.Mode()
+	if mode.IsDir() {
+		return e.mkdir(entry.Name)
+	}
+	if mode&fs.ModeType != 0 && mode&fs.ModeSymlink == 0 {
+		// Devices and other special files
+		return nil
+	}
+
+	rc, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if mode&fs.ModeSymlink != 0 {
+		target, err := io.ReadAll(io.LimitReader(rc, 4096))
+		if err != nil {
+			return err
+		}
+		return e.symlink(entry.Name, string(target))
+	}
+	if mode.Perm() == 0 {
+		// Archives made on Windows carry no permissions
+		mode = 0644
+	}
+	return e.file(entry.Name, mode&os.ModePerm, rc)
+}
@@ -3,71 +3,65 @@ package bckgrdDownload
 import (
 	"fmt"
 	"io"
-	"log"
-	"net/http"
 	"os"
-	"path"
-	"time"
+	"os/exec"
+	"wget/logging"
 )
 
-// Start handles the background download and logs the process to wget-log
-func Start(url string) error {
-	// Open log file to append logs
-	logFile, err := os.OpenFile("wget-log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		fmt.Println("Error creating log file:", err)
-		return err
-	}
-	defer logFile.Close()
-
-	// Set up logging to file
-	log.SetOutput(logFile)
+// LogFile is where background downloads write their output
+const LogFile = "wget-log"
 
-	// Start download and log details
-	startTime := time.Now()
-	logMessage(fmt.Sprintf("Start at %s", startTime.Format(time.RFC1123)))
-	logMessage(fmt.Sprintf("Sending request to download %s...", url))
+// childEnv marks the detached copy of the process
+const childEnv = "WGET_BACKGROUND_CHILD"
 
-	// Make the HTTP request to the provided URL
-	resp, err := http.Get(url)
-	if err != nil {
-		logMessage(fmt.Sprintf("Error: %v", err))
-		return err
+// Start prepares a background run (-B, --at, --every). Output goes to
+// wget-log unless a log file was already chosen with -o or -a. The program
+// is then started again in a new session without a terminal and Start
+// reports true: the caller exits and the copy does the work. In that copy
+// Start reports false and the run continues. input is written to the
+// standard input of the copy, e.g. a password typed at a prompt, so it
+// never shows in its arguments or environment.
+func Start(logOpts *logging.Options, input []byte) (bool, error) {
+	if logOpts.File == "" {
+		logOpts.File, logOpts.Append = LogFile, true
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		logMessage(fmt.Sprintf("Failed to download HTTP request: %s, Status Code: %d", url, resp.StatusCode))
-		return fmt.Errorf("HTTP request failed: %s: %d", url, resp.StatusCode)
+	if IsChild() {
+		return false, nil
 	}
 
-	// Determine the file path to save the content
-	fileName := path.Base(url)
-	file, err := os.Create(fileName)
+	executable, err := os.Executable()
 	if err != nil {
-		logMessage(fmt.Sprintf("Error creating file: %v", err))
-		return err
+		return false, err
 	}
-	defer file.Close()
-
-	// Copy content to the file
-	bytesWritten, err := io.Copy(file, resp.Body)
-	if err != nil {
-		logMessage(fmt.Sprintf("Error writing to file: %v", err))
-		return err
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), childEnv+"=1")
+	cmd.SysProcAttr = detachAttr()
+	var stdin io.WriteCloser
+	if input != nil {
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			return false, fmt.Errorf("cannot continue in background: %v", err)
+		}
 	}
-
-	// Log the download size and completion details
-	fileSizeMB := float64(bytesWritten) / (1024 * 1024)
-	logMessage(fmt.Sprintf("Content size: %d bytes [%.fMB]", bytesWritten, fileSizeMB))
-	logMessage(fmt.Sprintf("Saving file to: ./%s", fileName))
-	logMessage(fmt.Sprintf("Downloaded [%s]", url))
-	logMessage(fmt.Sprintf("Finished at %s", time.Now().Format(time.RFC1123)))
-
-	return nil
+	if err := cmd.Start(); err != nil {
+		return false, fmt.Errorf("cannot continue in background: %v", err)
+	}
+	if stdin != nil {
+		// The pipe holds far more than a line, so this doesn't wait for the copy
+		_, err := stdin.Write(input)
+		if closeErr := stdin.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			cmd.Process.Kill()
+			return false, fmt.Errorf("cannot continue in background: %v", err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Continuing in background, pid %d.\nOutput will be written to '%s'.\n", cmd.Process.Pid, logOpts.File)
+	cmd.Process.Release()
+	return true, nil
 }
 
-// logMessage is a helper function to log messages
-func logMessage(msg string) {
-	log.Println(msg)
+// IsChild reports whether this process is the detached copy made by Start
+func IsChild() bool {
+	return os.Getenv(childEnv) == "1"
 }
@@ -0,0 +1,131 @@
+package bckgrdDownload
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		want     []int
+	}{
+		{"*", 0, 5, []int{0, 1, 2, 3, 4, 5}},
+		{"3", 0, 59, []int{3}},
+		{"1,3,5", 0, 59, []int{1, 3, 5}},
+		{"10-13", 0, 59, []int{10, 11, 12, 13}},
+		{"*/15", 0, 59, []int{0, 15, 30, 45}},
+		{"*/5", 1, 12, []int{1, 6, 11}},
+		{"10-20/5", 0, 59, []int{10, 15, 20}},
+		{"50/4", 0, 59, []int{50, 54, 58}},
+		{"0,30-31,*/20", 0, 59, []int{0, 20, 30, 31, 40}},
+		{"0", 0, 0, []int{0}},
+		{"59", 0, 59, []int{59}},
+	}
+	for _, tt := range tests {
+		values, err := parseCronField(tt.field, tt.min, tt.max)
+		if err != nil {
+			t.Errorf("parseCronField(%q): %v", tt.field, err)
+			continue
+		}
+		var got []int
+		for v := range values {
+			got = append(got, v)
+		}
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCronField(%q, %d, %d) = %v, want %v", tt.field, tt.min, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"-1 * * * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"1,,2 * * * *",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	start := time.Date(2025, time.January, 15, 10, 7, 30, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{"* * * * *", start, []time.Time{at(1, 15, 10, 8), at(1, 15, 10, 9)}},
+		{"*/15 * * * *", start, []time.Time{at(1, 15, 10, 15), at(1, 15, 10, 30)}},
+		{"0 */6 * * *", start, []time.Time{at(1, 15, 12, 0), at(1, 15, 18, 0), at(1, 16, 0, 0)}},
+		// Strictly after: a start on a matching minute moves on
+		{"0 12 * * *", at(1, 15, 12, 0), []time.Time{at(1, 16, 12, 0)}},
+		{"30 2 * * 1-5", start, []time.Time{at(1, 16, 2, 30), at(1, 17, 2, 30), at(1, 20, 2, 30)}},
+		// 0 and 7 are both Sunday
+		{"0 0 * * 0", start, []time.Time{at(1, 19, 0, 0), at(1, 26, 0, 0)}},
+		{"0 0 * * 7", start, []time.Time{at(1, 19, 0, 0), at(1, 26, 0, 0)}},
+		{"0 0 1 * *", start, []time.Time{at(2, 1, 0, 0), at(3, 1, 0, 0)}},
+		{"0 0 31 * *", start, []time.Time{at(1, 31, 0, 0), at(3, 31, 0, 0), at(5, 31, 0, 0)}},
+		{"0 9 * 3,6 *", start, []time.Time{at(3, 1, 9, 0), at(3, 2, 9, 0)}},
+		// With both day fields restricted either one may match
+		{"0 0 20 * 5", start, []time.Time{at(1, 17, 0, 0), at(1, 20, 0, 0), at(1, 24, 0, 0)}},
+		// A day field starting with "*" doesn't count as restricted
+		{"0 0 */10 * 5", start, []time.Time{at(1, 31, 0, 0), at(2, 21, 0, 0), at(3, 21, 0, 0)}},
+		// "*/2" keeps the AND rule while the same days spelled "1-31/2" give OR
+		{"0 0 */2 * 1", start, []time.Time{at(1, 27, 0, 0), at(2, 3, 0, 0), at(2, 17, 0, 0)}},
+		{"0 0 1-31/2 * 1", start, []time.Time{at(1, 17, 0, 0), at(1, 19, 0, 0), at(1, 20, 0, 0), at(1, 21, 0, 0)}},
+		{"0 0 * * */3", start, []time.Time{at(1, 18, 0, 0), at(1, 19, 0, 0), at(1, 22, 0, 0)}},
+		// Leap days are four years apart at most
+		{"0 0 29 2 *", start, []time.Time{time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)}},
+	}
+	for _, tt := range tests {
+		spec, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		from := tt.from
+		for i, want := range tt.want {
+			got := spec.next(from)
+			if !got.Equal(want) {
+				t.Errorf("%q run %d after %s = %s, want %s", tt.expr, i+1, from.Format(time.RFC3339), got.Format(time.RFC3339), want.Format(time.RFC3339))
+				break
+			}
+			from = got
+		}
+	}
+}
+
+func TestCronNextImpossible(t *testing.T) {
+	spec, err := parseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if got := spec.next(start); got.Before(start.AddDate(5, 0, 0)) {
+		t.Errorf("30 February scheduled for %s", got)
+	}
+}
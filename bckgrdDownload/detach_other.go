@@ -0,0 +1,10 @@
+//go:build !unix
+
+package bckgrdDownload
+
+import "syscall"
+
+// detachAttr has nothing to add where there are no sessions
+func detachAttr() *syscall.SysProcAttr {
+	return nil
+}
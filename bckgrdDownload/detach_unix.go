@@ -0,0 +1,11 @@
+//go:build unix
+
+package bckgrdDownload
+
+import "syscall"
+
+// detachAttr starts the background copy in its own session, so it keeps
+// running when the terminal closes
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,159 @@
+package bckgrdDownload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"wget/exitStatus"
+	"wget/job"
+)
+
+// atLayouts are the accepted formats for --at, tried in order
+var atLayouts = []string{
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+// Schedule calls run on a timer. cfg.At delays the first run until the
+// given time and cfg.Every repeats it, either as a Go duration ("6h", "30m")
+// or a five-field cron expression ("0 */6 * * *"). Recurring runs use
+// timestamping so unchanged files are skipped. The outcome of each run is
+// written to the log. SIGINT or SIGTERM cancels the run in progress and
+// ends the schedule; it fails if no run had started by then.
+func Schedule(cfg *job.Config, run func(cfg *job.Config) error) error {
+	logger := cfg.Logger
+	at, every := cfg.At, cfg.Every
+
+	var first time.Time
+	if at != "" {
+		t, err := parseAt(at)
+		if err != nil {
+			return err
+		}
+		first = t
+	}
+
+	var next func(time.Time) time.Time
+	if every != "" {
+		n, err := parseEvery(every)
+		if err != nil {
+			return err
+		}
+		next = n
+	}
+
+	// Stop waiting for the next run, or the one in progress, when
+	// interrupted; a second signal ends the process the usual way
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	runAt := first
+	if runAt.IsZero() {
+		runAt = time.Now()
+		if next != nil && !isDuration(every) {
+			runAt = next(runAt)
+		}
+	}
+
+	// Recurring runs only fetch files that changed since the last one
+	runCfg := *cfg
+	runCfg.Timestamping = cfg.Timestamping || next != nil
+	runCfg.Context = ctx
+
+	var lastErr error
+	for n := 1; ; n++ {
+		logger.Info("Run scheduled", "run", n, "at", runAt.Format(time.RFC1123))
+		if !sleepUntil(ctx, runAt) {
+			logger.Info("Schedule stopped")
+			if n == 1 {
+				return exitStatus.Wrap(exitStatus.Generic, errors.New("interrupted before the scheduled run started"))
+			}
+			return lastErr
+		}
+
+		lastErr = run(&runCfg)
+		if lastErr != nil {
+			logger.Error("Run failed", "run", n, "err", lastErr)
+		} else {
+			logger.Info("Run succeeded", "run", n)
+		}
+
+		// A one-off --at run reports its own result
+		if next == nil || ctx.Err() != nil {
+			return lastErr
+		}
+		runAt = next(time.Now())
+	}
+}
+
+// Check parses the --at and --every values of cfg, so a mistake is reported
+// before the run detaches instead of only in wget-log
+func Check(cfg *job.Config) error {
+	if cfg.At != "" {
+		if _, err := parseAt(cfg.At); err != nil {
+			return err
+		}
+	}
+	if cfg.Every != "" {
+		if _, err := parseEvery(cfg.Every); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseAt reads a --at value in local time
+func parseAt(at string) (time.Time, error) {
+	for _, layout := range atLayouts {
+		if t, err := time.ParseInLocation(layout, at, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, exitStatus.Wrap(exitStatus.Parse, fmt.Errorf("invalid --at time %q (expected e.g. 2026-10-20T02:00)", at))
+}
+
+// parseEvery turns a --every value into a function returning the run after t
+func parseEvery(every string) (func(time.Time) time.Time, error) {
+	if d, err := time.ParseDuration(every); err == nil {
+		if d <= 0 {
+			return nil, exitStatus.Wrap(exitStatus.Parse, fmt.Errorf("invalid --every interval %q", every))
+		}
+		return func(t time.Time) time.Time { return t.Add(d) }, nil
+	}
+
+	spec, err := parseCron(every)
+	if err != nil {
+		return nil, exitStatus.Wrap(exitStatus.Parse, fmt.Errorf("invalid --every value %q: %v", every, err))
+	}
+	return spec.next, nil
+}
+
+// isDuration reports whether every is an interval rather than a cron expression
+func isDuration(every string) bool {
+	_, err := time.ParseDuration(every)
+	return err == nil
+}
+
+// sleepUntil waits for t and returns false if ctx is cancelled first
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,33 @@
+package bckgrdDownload
+
+import (
+	"testing"
+	"wget/exitStatus"
+	"wget/job"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		at, every string
+		ok        bool
+	}{
+		{"", "", true},
+		{"2026-10-20T02:00", "", true},
+		{"2026-10-20 02:00:30", "6h", true},
+		{"", "0 */6 * * *", true},
+		{"garbage", "", false},
+		{"", "99 * * * *", false},
+		{"", "-1h", false},
+		{"", "0s", false},
+		{"2026-10-20T02:00", "sometimes", false},
+	}
+	for _, tt := range tests {
+		err := Check(&job.Config{At: tt.at, Every: tt.every})
+		if (err == nil) != tt.ok {
+			t.Errorf("Check(at %q, every %q) = %v, want ok %v", tt.at, tt.every, err, tt.ok)
+		}
+		if err != nil && exitStatus.Of(err) != exitStatus.Parse {
+			t.Errorf("Check(at %q, every %q) exit status %d, want %d", tt.at, tt.every, exitStatus.Of(err), exitStatus.Parse)
+		}
+	}
+}
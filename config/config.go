@@ -0,0 +1,98 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"wget/options"
+)
+
+// ignoredKeys are GNU wgetrc commands for features this wget doesn't have
+// and doesn't need: FTP, the dot progress display, IRI and locale handling,
+// entropy sources and caching. Shared wgetrc files often set them, so they
+// are skipped without a warning. Keys are in NormalizeKey form.
+var ignoredKeys = map[string]bool{
+	"passiveftp": true, "ftpuser": true, "ftppassword": true, "ftpproxy": true,
+	"followftp": true, "glob": true, "removelisting": true, "retrsymlinks": true,
+	"preservepermissions": true, "ftpsimplicit": true, "ftpsresumessl": true,
+	"ftpsfallbacktoftp": true, "ftpscleardataconnection": true,
+	"dotstyle": true, "dotbytes": true, "dotspacing": true, "dotsinline": true,
+	"progress": true, "showprogress": true,
+	"robots": true, "iri": true, "localencoding": true, "remoteencoding": true, "locale": true,
+	"egdfile": true, "randomfile": true,
+	"cache": true, "dnscache": true, "httpkeepalive": true,
+}
+
+// File is a wgetrc file to read
+type File struct {
+	Path string
+	// Required files must exist; the system and user files are optional
+	Required bool
+}
+
+// Files lists the configuration files in the order they are applied: the
+// system wgetrc, the user's ~/.wgetrc and the file given with --config.
+// SYSTEM_WGETRC and WGETRC override the first two locations.
+func Files(extra string) []File {
+	system := os.Getenv("SYSTEM_WGETRC")
+	if system == "" {
+		system = "/etc/wgetrc"
+	}
+	files := []File{{Path: system}}
+
+	if user := os.Getenv("WGETRC"); user != "" {
+		files = append(files, File{Path: user, Required: true})
+	} else if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, File{Path: filepath.Join(home, ".wgetrc")})
+	}
+
+	if extra != "" {
+		files = append(files, File{Path: extra, Required: true})
+	}
+	return files
+}
+
+// Load reads a wgetrc file and passes every "key = value" line to apply.
+// Blank lines and lines starting with # are ignored. A line that can't be
+// applied doesn't stop the rest of the file; it is returned as a warning
+// that names the file and line number. GNU commands that don't apply here
+// are skipped, and "config" is refused: a wgetrc file can't load another.
+func Load(file File, apply func(key, value string) error) ([]string, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		if os.IsNotExist(err) && !file.Required {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot read config file: %v", err)
+	}
+	defer f.Close()
+
+	var warnings []string
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, err := options.ParseCommand(line)
+		switch {
+		case err != nil:
+		case ignoredKeys[options.NormalizeKey(key)]:
+			continue
+		case options.NormalizeKey(key) == "config":
+			err = fmt.Errorf("'%s' can only be given on the command line", key)
+		default:
+			err = apply(key, value)
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s:%d: %v", file.Path, lineNo, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return warnings, fmt.Errorf("error reading %s: %v", file.Path, err)
+	}
+	return warnings, nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"wget/options"
+)
+
+func TestLoad(t *testing.T) {
+	flags := options.NewParser("wget")
+	headers := flags.StringList("header", "", "")
+	limit := flags.String("limit-rate", "", "", "")
+	tries := flags.String("tries", "t", "", "")
+	flags.String("config", "", "", "")
+
+	if _, err := flags.Parse([]string{"--header", "X-Cli: 1", "-t", "3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rc := filepath.Join(t.TempDir(), "wgetrc")
+	os.WriteFile(rc, []byte(strings.Join([]string{
+		"# comment",
+		"passive_ftp = on",
+		"header = X-Old: 1",
+		"header =",
+		"header = X-Rc: 1",
+		"limit_rate = 300k",
+		"tries = 10",
+		"config = /etc/other",
+		"no_such_option = 1",
+		"missing equals",
+	}, "\n")), 0644)
+
+	warnings, err := Load(File{Path: rc}, flags.SetDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantWarnings := []string{
+		rc + ":8: 'config' can only be given on the command line",
+		rc + ":9: unknown command 'no_such_option'",
+		rc + ":10: invalid command 'missing equals' (expected key = value)",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("warnings:\n%q\nwant\n%q", warnings, wantWarnings)
+	}
+	if want := []string{"X-Rc: 1", "X-Cli: 1"}; !reflect.DeepEqual(*headers, want) {
+		t.Errorf("headers %q, want %q", *headers, want)
+	}
+	if *limit != "300k" {
+		t.Errorf("limit_rate %q, want 300k from the file", *limit)
+	}
+	if *tries != "3" {
+		t.Errorf("tries %q, want 3 from the command line", *tries)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+	apply := func(key, value string) error { return nil }
+	if _, err := Load(File{Path: path}, apply); err != nil {
+		t.Errorf("optional file: %v", err)
+	}
+	if _, err := Load(File{Path: path, Required: true}, apply); err == nil {
+		t.Error("expected an error for a missing required file")
+	}
+}
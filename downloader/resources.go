@@ -1,20 +1,30 @@
 package downloader
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
+	"wget/exitStatus"
+	"wget/job"
+	"wget/rateDownload"
 
 	"golang.org/x/net/html"
 )
 
 // DownloadResources extracts and downloads all resources from a given HTML page.
-func DownloadResources(htmlContent, baseURL, saveDir string, excludeDirs []string) error {
+// Rejected extensions, excluded directories and the rate limit come from cfg.
+// Failed resources are skipped; the returned error carries the combined
+// exit status of all of them.
+func DownloadResources(htmlContent, baseURL, saveDir string, cfg *job.Config) error {
+	logger := cfg.Logger
+	status, failed := exitStatus.Success, 0
 	links := extractLinks(htmlContent, baseURL)
 
 	// Extract and download images from inline <style> blocks
@@ -22,15 +32,19 @@ func DownloadResources(htmlContent, baseURL, saveDir string, excludeDirs []strin
 	links = append(links, cssImages...)
 
 	for _, link := range links {
-		// Skip excluded directories
-		if shouldExclude(link, excludeDirs) {
-			fmt.Println("Skipping excluded directory:", link)
+		// Skip excluded directories and rejected file types
+		if reason := Skipped(link, cfg); reason != "" {
+			logger.Info(reason, "url", link)
 			continue
 		}
 
-		err := downloadResource(link, saveDir)
+		err := cfg.Retry(link, func(ctx context.Context) error {
+			return downloadResource(ctx, link, saveDir, cfg)
+		})
 		if err != nil {
-			fmt.Println("Error downloading:", link, "-", err)
+			logger.Error("Error downloading", "url", link, "err", err)
+			status = exitStatus.Combine(status, exitStatus.Of(err))
+			failed++
 		}
 	}
 
@@ -42,13 +56,58 @@ func DownloadResources(htmlContent, baseURL, saveDir string, excludeDirs []strin
 	indexPath := filepath.Join(saveDir, "index.html")
 	err := os.WriteFile(indexPath, []byte(htmlContent), 0644)
 	if err != nil {
-		return fmt.Errorf("failed to update index.html: %v", err)
+		return exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("failed to update index.html: %v", err))
 	}
 
-	fmt.Println("Updated index.html with correct offline references.")
+	logger.Info("Updated index.html with correct offline references")
+	if failed > 0 {
+		return exitStatus.Wrap(status, fmt.Errorf("%d resources failed to download", failed))
+	}
 	return nil
 }
 
+// Skipped explains why -X or -R leaves link out of the mirror, or returns
+// "" when it is fetched
+func Skipped(link string, cfg *job.Config) string {
+	switch {
+	case shouldExclude(link, cfg.Exclude):
+		return "Skipping excluded directory"
+	case shouldReject(link, cfg.Reject):
+		return "Skipping rejected file type"
+	}
+	return ""
+}
+
+// PageLinks returns every link of an HTML page: the resources the mirror
+// downloads and the anchors to other pages. Fragments are dropped.
+func PageLinks(htmlContent, baseURL string) []string {
+	links := extractLinks(htmlContent, baseURL)
+	links = append(links, extractImagesFromCSSContent(htmlContent, baseURL)...)
+
+	tokenizer := html.NewTokenizer(strings.NewReader(htmlContent))
+	for tt := tokenizer.Next(); tt != html.ErrorToken; tt = tokenizer.Next() {
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		if token.Data != "a" && token.Data != "iframe" {
+			continue
+		}
+		for _, attr := range token.Attr {
+			if attr.Key == "href" || attr.Key == "src" {
+				if link := resolveURL(strings.TrimSpace(attr.Val), baseURL); link != "" {
+					links = append(links, link)
+				}
+			}
+		}
+	}
+
+	for i, link := range links {
+		links[i], _, _ = strings.Cut(link, "#")
+	}
+	return links
+}
+
 // shouldExclude checks if a URL belongs to any excluded directory
 func shouldExclude(resourceURL string, excludeDirs []string) bool {
 	for _, dir := range excludeDirs {
@@ -59,6 +118,21 @@ func shouldExclude(resourceURL string, excludeDirs []string) bool {
 	return false
 }
 
+// shouldReject checks if a URL ends in one of the rejected extensions
+func shouldReject(resourceURL string, rejectExtensions []string) bool {
+	u, err := url.Parse(resourceURL)
+	if err != nil {
+		return false
+	}
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(u.Path)), ".")
+	for _, reject := range rejectExtensions {
+		if ext != "" && ext == strings.TrimPrefix(strings.ToLower(strings.TrimSpace(reject)), ".") {
+			return true
+		}
+	}
+	return false
+}
+
 // extractLinks finds all valid resource links in the HTML
 func extractLinks(htmlContent, baseURL string) []string {
 	var links []string
@@ -88,7 +162,6 @@ func extractLinks(htmlContent, baseURL string) []string {
 			}
 		}
 	}
-	return links
 }
 
 // extractStyleContent extracts raw CSS from a <style> block
@@ -111,16 +184,16 @@ func extractStyleContent(tokenizer *html.Tokenizer) string {
 // extractImagesFromCSSContent extracts background-image URLs from CSS content
 func extractImagesFromCSSContent(cssContent, baseURL string) []string {
 	var images []string
-	
+
 	// Match all background-image declarations
 	re := regexp.MustCompile(`background-image\s*:\s*[^;]*`)
 	matches := re.FindAllString(cssContent, -1)
-	
+
 	for _, match := range matches {
 		// Extract URLs from within url() functions
 		urlRe := regexp.MustCompile(`url\(['"]?([^'")]+)['"]?\)`)
 		urlMatches := urlRe.FindAllStringSubmatch(match, -1)
-		
+
 		for _, urlMatch := range urlMatches {
 			if len(urlMatch) > 1 {
 				// Clean and resolve each URL
@@ -158,54 +231,91 @@ func resolveURL(imgURL, baseURL string) string {
 	if strings.HasPrefix(imgURL, "http://") || strings.HasPrefix(imgURL, "https://") {
 		return imgURL
 	}
-	
+
 	// Parse the base URL
 	base, err := url.Parse(baseURL)
 	if err != nil {
 		return ""
 	}
-	
+
 	// Resolve the relative URL against the base
 	absURL, err := base.Parse(imgURL)
 	if err != nil {
 		return ""
 	}
-	
+
 	return absURL.String()
 }
 
-
 // downloadResource downloads CSS, JS, and image files
-func downloadResource(fileURL, saveDir string) error {
-	resp, err := http.Get(fileURL)
+func downloadResource(ctx context.Context, fileURL, saveDir string, cfg *job.Config) error {
+	fileName := filepath.Base(fileURL)
+	filePath := filepath.Join(saveDir, fileName)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return err
+	}
+	// With timestamping, e.g. a recurring mirror, unchanged resources are kept
+	var modTime time.Time
+	if cfg.Timestamping {
+		if info, err := os.Stat(filePath); err == nil {
+			modTime = info.ModTime()
+			req.Header.Set("If-Modified-Since", modTime.UTC().Format(http.TimeFormat))
+		}
+	}
+
+	resp, err := cfg.Client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified || (!modTime.IsZero() && !isNewer(resp, modTime)) {
+		cfg.Logger.Info("Not modified, keeping local file", "path", filePath)
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return exitStatus.Wrap(exitStatus.ForHTTPStatus(resp.StatusCode), fmt.Errorf("server responded with %s", resp.Status))
+	}
+
 	contentType := resp.Header.Get("Content-Type")
 	if strings.Contains(contentType, "text/html") {
 		return nil // Avoid downloading extra HTML pages
 	}
 
-	fileName := filepath.Base(fileURL)
-	filePath := filepath.Join(saveDir, fileName)
-
 	outFile, err := os.Create(filePath)
 	if err != nil {
 		return err
 	}
 	defer outFile.Close()
 
-	_, err = io.Copy(outFile, resp.Body)
+	_, err = rateDownload.Copy(outFile, resp.Body, resp.ContentLength, cfg.RateLimit, cfg.ShowProgress)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Downloaded:", filePath)
+	if cfg.Timestamping {
+		if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+			outFile.Close()
+			os.Chtimes(filePath, lastModified, lastModified)
+		}
+	}
+
+	cfg.Logger.Info("Downloaded", "path", filePath)
 	return nil
 }
 
+// isNewer reports whether the response carries a Last-Modified time after local.
+// Servers that don't send Last-Modified are always treated as newer.
+func isNewer(resp *http.Response, local time.Time) bool {
+	lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return true
+	}
+	return lastModified.After(local)
+}
+
 // adjustLinks modifies links for offline browsing
 func adjustLinks(htmlContent, baseURL, saveDir string) string {
 	tokenizer := html.NewTokenizer(strings.NewReader(htmlContent))
@@ -240,7 +350,6 @@ func adjustLinks(htmlContent, baseURL, saveDir string) string {
 	}
 }
 
-
 // adjustCSSLinks modifies inline <style> blocks to reference local files
 func adjustCSSLinks(htmlContent, baseURL, saveDir string) string {
 	re := regexp.MustCompile(`background-image\s*:\s*url\(['"]?([^'")]+)['"]?\)`)
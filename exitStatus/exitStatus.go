@@ -0,0 +1,130 @@
+package exitStatus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"syscall"
+)
+
+// Exit codes documented by GNU wget
+const (
+	Success     = 0
+	Generic     = 1
+	Parse       = 2
+	FileIO      = 3
+	Network     = 4
+	SSL         = 5
+	Auth        = 6
+	Protocol    = 7
+	ServerError = 8
+
+	// Timeout is not a GNU wget code: DNS, connect and read timeouts and
+	// an exhausted --deadline get their own status so scripts can retry
+	Timeout = 9
+)
+
+// Error attaches an exit code to an error
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+// Wrap tags err with code. It returns nil when err is nil.
+func Wrap(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Of works out the exit code for err. Codes attached with Wrap win, e.g.
+// Protocol for garbled responses and redirect loops; otherwise the error
+// is classified by type.
+func Of(err error) int {
+	if err == nil {
+		return Success
+	}
+
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	var (
+		recordErr    tls.RecordHeaderError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		invalidErr   x509.CertificateInvalidError
+		hostnameErr  x509.HostnameError
+		pathErr      *fs.PathError
+		netErr       net.Error
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return Timeout
+	case errors.As(err, &recordErr), errors.As(err, &verifyErr), errors.As(err, &authorityErr),
+		errors.As(err, &invalidErr), errors.As(err, &hostnameErr):
+		return SSL
+	case errors.As(err, &pathErr):
+		return FileIO
+	case errors.As(err, &netErr):
+		return Network
+	}
+	return Generic
+}
+
+// ForHTTPStatus returns the exit code for an HTTP error response
+func ForHTTPStatus(statusCode int) int {
+	switch {
+	case statusCode == 401 || statusCode == 407:
+		return Auth
+	case statusCode >= 400:
+		return ServerError
+	case statusCode >= 300:
+		// A redirect that wasn't followed
+		return Protocol
+	}
+	return Success
+}
+
+// Retryable reports whether a download that failed with err is worth trying
+// again: timeouts and dropped connections are, while refused connections,
+// unknown hosts, missing sockets and every non-network failure are not.
+func Retryable(err error) bool {
+	var dnsErr *net.DNSError
+	switch code := Of(err); {
+	case code == Timeout:
+		return true
+	case code != Network && !errors.Is(err, io.ErrUnexpectedEOF):
+		return false
+	case errors.As(err, &dnsErr), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, fs.ErrNotExist):
+		return false
+	}
+	return true
+}
+
+// Combine merges the codes of two failures the way wget does for a whole
+// run: apart from 0 and 1, lower codes take precedence over higher ones.
+func Combine(a, b int) int {
+	switch {
+	case a == Success:
+		return b
+	case b == Success:
+		return a
+	case a == Generic:
+		return b
+	case b == Generic:
+		return a
+	case a < b:
+		return a
+	}
+	return b
+}
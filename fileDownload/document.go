@@ -0,0 +1,73 @@
+package fileDownload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+	"wget/job"
+	"wget/rateDownload"
+)
+
+// toDocument downloads url into cfg.Document, after whatever the earlier
+// URLs of the run wrote there. Data already written can't be taken back,
+// so a retried attempt skips the part of the body a failed one delivered.
+func toDocument(url string, cfg *job.Config, startTime time.Time) (string, error) {
+	logger := cfg.Logger
+	out := &resumeWriter{w: cfg.Document}
+
+	err := cfg.Retry(url, func(ctx context.Context) error {
+		out.pos = 0
+		resp, err := get(ctx, url, cfg, time.Time{})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		_, err = rateDownload.Copy(out, resp.Body, resp.ContentLength, cfg.RateLimit, cfg.ShowProgress)
+		if err != nil {
+			logger.Error("Error writing output", "path", documentName(cfg), "err", err)
+		}
+		return err
+	})
+	if err != nil {
+		return documentName(cfg), err
+	}
+
+	endTime := time.Now()
+	logger.Info("Download finished", "time", endTime.Format("2006-01-02 15:04:05"))
+	logger.Info("Written to", "path", documentName(cfg), "bytes", out.done)
+	logger.Info("Time taken", "seconds", fmt.Sprintf("%.2f", endTime.Sub(startTime).Seconds()))
+	return documentName(cfg), nil
+}
+
+// documentName is how the -O document is shown in logs and hook results
+func documentName(cfg *job.Config) string {
+	if cfg.Output == "-" {
+		return "standard output"
+	}
+	return cfg.Output
+}
+
+// resumeWriter passes a body on to w, dropping the bytes an earlier
+// attempt has already written
+type resumeWriter struct {
+	w io.Writer
+	// pos is the offset in the body of the current attempt
+	pos int64
+	// done is how much of the body has been written to w
+	done int64
+}
+
+func (r *resumeWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if skip := r.done - r.pos; skip > 0 {
+		skip = min(skip, int64(n))
+		p = p[skip:]
+		r.pos += skip
+	}
+	written, err := r.w.Write(p)
+	r.pos += int64(written)
+	r.done = max(r.done, r.pos)
+	return n - len(p) + written, err
+}
@@ -0,0 +1,129 @@
+package fileDownload
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// shortWriter accepts at most limit bytes and then fails
+type shortWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.Len(); len(p) > room {
+		n, _ := w.Buffer.Write(p[:max(room, 0)])
+		return n, errors.New("disk full")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestResumeWriter(t *testing.T) {
+	var out bytes.Buffer
+	r := &resumeWriter{w: &out}
+
+	// The first attempt delivers "hello wo" before failing
+	for _, chunk := range []string{"hel", "lo wo"} {
+		if n, err := r.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	// The retry starts the body again; only the new part is written
+	r.pos = 0
+	for _, chunk := range []string{"hello", " w", "orld", "!"} {
+		if n, err := r.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if out.String() != "hello world!" || r.done != 12 {
+		t.Errorf("wrote %q (done %d), want %q", out.String(), r.done, "hello world!")
+	}
+
+	// A retry that ends early adds nothing
+	r.pos = 0
+	r.Write([]byte("hello"))
+	if out.String() != "hello world!" || r.done != 12 {
+		t.Errorf("after a short retry: %q (done %d)", out.String(), r.done)
+	}
+}
+
+func TestResumeWriterShortWrite(t *testing.T) {
+	w := &shortWriter{limit: 4}
+	r := &resumeWriter{w: w}
+	n, err := r.Write([]byte("abcdef"))
+	if n != 4 || err == nil {
+		t.Fatalf("Write = %d, %v; want 4 and an error", n, err)
+	}
+
+	// The retry skips what was written, even within one chunk
+	w.limit = 100
+	r.pos = 0
+	if n, err := r.Write([]byte("abcdef")); n != 6 || err != nil {
+		t.Fatalf("Write after retry = %d, %v", n, err)
+	}
+	if w.String() != "abcdef" || r.done != 6 {
+		t.Errorf("wrote %q (done %d)", w.String(), r.done)
+	}
+}
+
+func TestToDocument(t *testing.T) {
+	// /flaky drops the connection halfway on its first request
+	var mu sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			io.WriteString(w, "first\n")
+		case "/b":
+			io.WriteString(w, "second\n")
+		case "/flaky":
+			mu.Lock()
+			attempts++
+			first := attempts == 1
+			mu.Unlock()
+			body := "0123456789\n"
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			if first {
+				io.WriteString(w, body[:5])
+				w.(http.Flusher).Flush()
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			io.WriteString(w, body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var document bytes.Buffer
+	cfg := newTestConfig(t)
+	cfg.Output, cfg.URLs, cfg.Document = "out.txt", []string{"a", "b"}, &document
+	cfg.Tries, cfg.WaitRetry = 3, time.Millisecond
+
+	for _, path := range []string{"/a", "/flaky", "/missing", "/b"} {
+		name, err := Start(server.URL+path, cfg)
+		if (err != nil) != (path == "/missing") {
+			t.Errorf("%s: %v", path, err)
+		}
+		if name != "out.txt" {
+			t.Errorf("%s: document name %q", path, name)
+		}
+	}
+	// Each body follows the last, the retried one written exactly once
+	if want := "first\n0123456789\nsecond\n"; document.String() != want {
+		t.Errorf("document %q, want %q", document.String(), want)
+	}
+	if attempts != 2 {
+		t.Errorf("%d attempts at /flaky, want 2", attempts)
+	}
+}
@@ -1,77 +1,189 @@
 package fileDownload
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
+	"wget/archiveExtract"
+	"wget/exitStatus"
+	"wget/httpClient"
+	"wget/job"
+	"wget/rateDownload"
 )
 
-// Start handles the file download
-func Start(url, output, saveDir string) {
+// Start handles the file download and returns the path the file was saved to.
+// It honors the output name, save directory, no-clobber, timestamping and
+// rate limit settings in cfg.
+func Start(url string, cfg *job.Config) (string, error) {
+	logger := cfg.Logger
 	startTime := time.Now()
-	fmt.Println("Start time:", startTime.Format("2006-01-02 15:04:05"))
+	logger.Info("Download started", "url", url, "time", startTime.Format("2006-01-02 15:04:05"))
 
-	// Send HTTP request
-	resp, err := http.Get(url)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return
+	// -O - and -O with several URLs write every body into one document
+	if cfg.Document != nil {
+		return toDocument(url, cfg, startTime)
 	}
-	defer resp.Body.Close()
-
-	fmt.Println("HTTP Response:", resp.Status)
 
 	// Get filename from URL if not provided
+	output := cfg.Output
 	if output == "" {
 		output = filepath.Base(url)
 	}
 
-	// Handle `-P` flag and expand `~`
-	if saveDir != "" {
-		if strings.HasPrefix(saveDir, "~") {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				fmt.Println("Error getting home directory:", err)
-				return
-			}
-			saveDir = filepath.Join(homeDir, saveDir[1:])
-		}
+	// --pipe-to hands the body to a command instead of saving it
+	if cfg.PipeTo != "" {
+		return toCommand(url, output, cfg, startTime)
+	}
 
+	// Handle `-P` flag
+	if saveDir := cfg.SaveDir; saveDir != "" {
 		// Create directory if it does not exist
 		err := os.MkdirAll(saveDir, os.ModePerm)
 		if err != nil {
-			fmt.Println("Error creating directory:", err)
-			return
+			logger.Error("Error creating directory", "dir", saveDir, "err", err)
+			return "", err
 		}
 
 		output = filepath.Join(saveDir, output)
 	}
 
+	if cfg.NoClobber {
+		if _, err := os.Stat(output); err == nil {
+			logger.Info("File already there; not retrieving", "path", output)
+			return output, nil
+		}
+	}
+
+	// Stalled or dropped transfers start over while the retry policy allows
+	var path string
+	err := cfg.Retry(url, func(ctx context.Context) error {
+		var err error
+		path, err = fetch(ctx, url, output, cfg)
+		return err
+	})
+	if err != nil {
+		return path, err
+	}
+	if path == "" {
+		// The local copy is up to date
+		return output, nil
+	}
+
+	endTime := time.Now()
+	logger.Info("Download finished", "time", endTime.Format("2006-01-02 15:04:05"))
+	logger.Info("File saved", "path", output)
+	logger.Info("Time taken", "seconds", fmt.Sprintf("%.2f", endTime.Sub(startTime).Seconds()))
+
+	// Unpack archives next to the download unless told where
+	if cfg.Extract {
+		dir := cfg.ExtractDir
+		if dir == "" {
+			dir = filepath.Dir(output)
+		}
+		if err := archiveExtract.Start(output, dir, logger); err != nil {
+			logger.Error("Extraction failed", "path", output, "err", err)
+			return output, err
+		}
+	}
+	return output, nil
+}
+
+// fetch makes one attempt at downloading url to output. It returns an
+// empty path when the local copy is already up to date.
+func fetch(ctx context.Context, url, output string, cfg *job.Config) (string, error) {
+	logger := cfg.Logger
+
+	// Ask the server to skip the body if our copy is up to date
+	var localModTime time.Time
+	if cfg.Timestamping {
+		if info, err := os.Stat(output); err == nil {
+			localModTime = info.ModTime()
+		}
+	}
+
+	resp, err := get(ctx, url, cfg, localModTime)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified || (!localModTime.IsZero() && !isNewer(resp, localModTime)) {
+		logger.Info("File not modified on server, skipping", "path", output)
+		return "", nil
+	}
+
 	// Create file
 	file, err := os.Create(output)
 	if err != nil {
-		fmt.Println("Error creating file:", err)
-		return
+		logger.Error("Error creating file", "path", output, "err", err)
+		return "", err
 	}
 	defer file.Close()
 
 	// Display content length
 	contentLength := resp.ContentLength
-	fmt.Printf("Content Length: %.2f MB (%d bytes)\n", float64(contentLength)/(1024*1024), contentLength)
+	logger.Info("Content length", "bytes", contentLength, "size", fmt.Sprintf("%.2f MB", float64(contentLength)/(1024*1024)))
 
 	// Download file with progress
-	_, err = io.Copy(file, resp.Body)
+	_, err = rateDownload.Copy(file, resp.Body, contentLength, cfg.RateLimit, cfg.ShowProgress)
 	if err != nil {
-		fmt.Println("Error writing file:", err)
-		return
+		logger.Error("Error writing file", "path", output, "err", err)
+		return output, err
 	}
 
-	endTime := time.Now()
-	fmt.Println("End time:", endTime.Format("2006-01-02 15:04:05"))
-	fmt.Println("File saved as:", output)
-	fmt.Printf("Time taken: %.2f seconds\n", endTime.Sub(startTime).Seconds())
+	// Keep the server's modification time so the next run can compare against it
+	if cfg.Timestamping {
+		if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+			file.Close()
+			os.Chtimes(output, lastModified, lastModified)
+		}
+	}
+
+	return output, nil
+}
+
+// get sends the request for url and checks the response status. A set
+// modTime asks the server to answer 304 if the file hasn't changed since.
+func get(ctx context.Context, url string, cfg *job.Config, modTime time.Time) (*http.Response, error) {
+	logger := cfg.Logger
+	req, err := httpClient.NewRequest(cfg.Method, url, cfg.BodyData, cfg.BodyFile)
+	if err != nil {
+		logger.Error("Invalid request", "url", url, "err", err)
+		return nil, err
+	}
+	if !modTime.IsZero() {
+		req.Header.Set("If-Modified-Since", modTime.UTC().Format(http.TimeFormat))
+	}
+
+	// Send HTTP request
+	resp, err := cfg.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		logger.Error("Request failed", "url", url, "err", err)
+		return nil, err
+	}
+
+	logger.Info("HTTP response", "status", resp.Status)
+	if resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		err := exitStatus.Wrap(exitStatus.ForHTTPStatus(resp.StatusCode), fmt.Errorf("server responded with %s", resp.Status))
+		logger.Error("Download failed", "url", url, "err", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// isNewer reports whether the response carries a Last-Modified time after local.
+// Servers that don't send Last-Modified are always treated as newer.
+func isNewer(resp *http.Response, local time.Time) bool {
+	lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return true
+	}
+	return lastModified.After(local)
 }
@@ -0,0 +1,93 @@
+package fileDownload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+	"wget/exitStatus"
+	"wget/hooks"
+	"wget/job"
+	"wget/rateDownload"
+)
+
+// PipeError reports a --pipe-to command that exited with a failure status
+type PipeError struct {
+	Command  string
+	ExitCode int
+}
+
+func (e *PipeError) Error() string {
+	return fmt.Sprintf("command %q exited with status %d", e.Command, e.ExitCode)
+}
+
+// toCommand streams url into the stdin of the --pipe-to command, with the
+// usual progress bar and rate limit. The command starts with the first
+// successful response; retries carry on where the failed attempt stopped,
+// since the command has already consumed what was sent.
+func toCommand(url, name string, cfg *job.Config, startTime time.Time) (string, error) {
+	logger := cfg.Logger
+	command := strings.NewReplacer("{url}", hooks.ShellQuote(url), "{name}", hooks.ShellQuote(name)).Replace(cfg.PipeTo)
+
+	var cmd *exec.Cmd
+	var stdin io.WriteCloser
+	out := &resumeWriter{}
+	err := cfg.Retry(url, func(ctx context.Context) error {
+		out.pos = 0
+		resp, err := get(ctx, url, cfg, time.Time{})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if cmd == nil {
+			cmd = exec.Command("sh", "-c", command)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if stdin, err = cmd.StdinPipe(); err == nil {
+				err = cmd.Start()
+			}
+			if err != nil {
+				return exitStatus.Wrap(exitStatus.Generic, fmt.Errorf("cannot run %q: %v", command, err))
+			}
+			logger.Info("Piping to command", "command", command)
+			out.w = stdin
+		}
+
+		_, err = rateDownload.Copy(out, resp.Body, resp.ContentLength, cfg.RateLimit, cfg.ShowProgress)
+		if errors.Is(err, syscall.EPIPE) {
+			err = exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("command %q stopped reading its input", command))
+		}
+		return err
+	})
+	if cmd == nil {
+		return command, err
+	}
+
+	// The command sees end of input and its exit status decides the result
+	stdin.Close()
+	if waitErr := cmd.Wait(); waitErr != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(waitErr, &exitErr) {
+			err = errors.Join(err, waitErr)
+		} else if err == nil || exitStatus.Of(err) == exitStatus.FileIO {
+			// A command that fails usually stops reading too; its status says more
+			err = exitStatus.Wrap(exitStatus.Generic, &PipeError{Command: command, ExitCode: exitErr.ExitCode()})
+		}
+	}
+	if err != nil {
+		logger.Error("Pipe failed", "url", url, "command", command, "err", err)
+		return command, err
+	}
+
+	endTime := time.Now()
+	logger.Info("Download finished", "time", endTime.Format("2006-01-02 15:04:05"))
+	logger.Info("Piped to command", "command", command, "bytes", out.done)
+	logger.Info("Time taken", "seconds", fmt.Sprintf("%.2f", endTime.Sub(startTime).Seconds()))
+	return command, nil
+}
@@ -0,0 +1,94 @@
+package fileDownload
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"wget/exitStatus"
+	"wget/httpClient"
+	"wget/job"
+)
+
+// newTestConfig returns a run configuration with a quiet logger
+func newTestConfig(t *testing.T) *job.Config {
+	t.Helper()
+	client, err := httpClient.New(httpClient.Options{
+		NetrcFile: filepath.Join(t.TempDir(), "netrc"),
+		NoHSTS:    true,
+		NoCookies: true,
+		NoProxy:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &job.Config{Client: client, Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), Tries: 1}
+}
+
+func TestPipeTo(t *testing.T) {
+	large := strings.Repeat("x", 4<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small.txt":
+			io.WriteString(w, "hello\n")
+		case "/large.bin":
+			io.WriteString(w, large)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		path    string
+		command string
+		code    int
+		// pipeStatus is the exit status of a failed command, -1 for none
+		pipeStatus int
+		output     string
+	}{
+		{"success", "/small.txt", "cat > " + dir + "/{name}", exitStatus.Success, -1, "hello\n"},
+		{"name and url", "/small.txt", "cat > /dev/null; echo {name} {url} > " + dir + "/small.txt", exitStatus.Success, -1, "small.txt " + server.URL + "/small.txt\n"},
+		{"command fails", "/small.txt", "cat > " + dir + "/{name}; exit 3", exitStatus.Generic, 3, "hello\n"},
+		{"command stops reading and fails", "/large.bin", "head -c 1 > /dev/null; exit 4", exitStatus.Generic, 4, ""},
+		{"command stops reading", "/large.bin", "head -c 1 > /dev/null", exitStatus.FileIO, -1, ""},
+		{"download fails", "/missing", "cat > " + dir + "/missing", exitStatus.ServerError, -1, ""},
+	}
+	for _, tt := range tests {
+		os.RemoveAll(dir)
+		os.MkdirAll(dir, 0755)
+		cfg := newTestConfig(t)
+		cfg.PipeTo = tt.command
+
+		_, err := Start(server.URL+tt.path, cfg)
+		if code := exitStatus.Of(err); code != tt.code {
+			t.Errorf("%s: exit status %d (%v), want %d", tt.name, code, err, tt.code)
+		}
+		var pipeErr *PipeError
+		switch {
+		case tt.pipeStatus < 0 && errors.As(err, &pipeErr):
+			t.Errorf("%s: unexpected %v", tt.name, pipeErr)
+		case tt.pipeStatus >= 0 && (!errors.As(err, &pipeErr) || pipeErr.ExitCode != tt.pipeStatus):
+			t.Errorf("%s: error %v, want a command exit status of %d", tt.name, err, tt.pipeStatus)
+		}
+
+		if tt.output != "" {
+			data, _ := os.ReadFile(filepath.Join(dir, filepath.Base(tt.path)))
+			if !bytes.Equal(data, []byte(tt.output)) {
+				t.Errorf("%s: command wrote %q, want %q", tt.name, data, tt.output)
+			}
+		}
+	}
+	// A failed download never starts the command
+	if _, err := os.Stat(filepath.Join(dir, "missing")); err == nil {
+		t.Error("the command ran for a failed download")
+	}
+}
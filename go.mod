@@ -2,4 +2,19 @@ module wget
 
 go 1.23.2
 
-require golang.org/x/net v0.37.0
+require (
+	github.com/andybalholm/brotli v1.2.0
+	github.com/dop251/goja v0.0.0-20241024094426-79f3a7efcdbd
+	github.com/klauspost/compress v1.18.0
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/net v0.37.0
+	golang.org/x/term v0.30.0
+)
+
+require (
+	github.com/dlclark/regexp2 v1.11.4 // indirect
+	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
+	github.com/google/pprof v0.0.0-20230207041349-798e818bf904 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
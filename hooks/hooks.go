@@ -0,0 +1,126 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Result describes a finished download or mirror job
+type Result struct {
+	URL      string    `json:"url"`
+	Path     string    `json:"path"`
+	Mode     string    `json:"mode"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Duration float64   `json:"duration_seconds"`
+	// PipeExitStatus is the exit status of the --pipe-to command, when one ran
+	PipeExitStatus *int `json:"pipe_exit_status,omitempty"`
+}
+
+// Hooks holds the commands to run once a download or mirror completes
+type Hooks struct {
+	// Exec is a command template such as "cmd {path} {url} {status}"
+	Exec string
+	// Webhook is a URL that receives a JSON summary of the result
+	Webhook string
+	// Transport sends the webhook request, so it goes through the same
+	// proxy as the downloads; nil uses the default transport
+	Transport http.RoundTripper
+	// Stdout receives the output of Exec; nil means standard output
+	Stdout io.Writer
+}
+
+// NewResult builds a Result for url, marking it as failed when err is set
+func NewResult(mode, url, path string, started time.Time, err error) Result {
+	finished := time.Now()
+	r := Result{
+		URL:      url,
+		Path:     path,
+		Mode:     mode,
+		Status:   "success",
+		Started:  started,
+		Finished: finished,
+		Duration: finished.Sub(started).Seconds(),
+	}
+	if err != nil {
+		r.Status = "failed"
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// Enabled reports whether any hook is configured
+func (h Hooks) Enabled() bool {
+	return h.Exec != "" || h.Webhook != ""
+}
+
+// Fire runs the completion command and posts the webhook for r. Hook
+// failures are reported on their own so they aren't mistaken for a failed
+// download.
+func (h Hooks) Fire(r Result, logger *slog.Logger) {
+	if h.Exec != "" {
+		if err := h.runCommand(r); err != nil {
+			logger.Error("Completion hook failed", "url", r.URL, "err", err)
+		} else {
+			logger.Debug("Completion hook ran", "url", r.URL)
+		}
+	}
+	if h.Webhook != "" {
+		if err := h.postWebhook(r); err != nil {
+			logger.Error("Webhook failed", "url", r.URL, "webhook", h.Webhook, "err", err)
+		} else {
+			logger.Debug("Webhook sent", "url", r.URL, "webhook", h.Webhook)
+		}
+	}
+}
+
+// runCommand fills in the {path}, {url} and {status} placeholders and runs the command through the shell
+func (h Hooks) runCommand(r Result) error {
+	replacer := strings.NewReplacer(
+		"{path}", ShellQuote(r.Path),
+		"{url}", ShellQuote(r.URL),
+		"{status}", ShellQuote(r.Status),
+	)
+	cmd := exec.Command("sh", "-c", replacer.Replace(h.Exec))
+	cmd.Stdout = os.Stdout
+	if h.Stdout != nil {
+		cmd.Stdout = h.Stdout
+	}
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// postWebhook sends r as JSON to the webhook URL
+func (h Hooks) postWebhook(r Result) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Transport: h.Transport, Timeout: 30 * time.Second}
+	resp, err := client.Post(h.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("server responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// ShellQuote wraps s in single quotes so it is passed to the shell as one word
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,258 @@
+package httpClient
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// credentials is a user name and password for one host
+type credentials struct {
+	user     string
+	password string
+}
+
+// challenge is one scheme offered in a WWW-Authenticate header
+type challenge struct {
+	scheme string
+	params map[string]string
+}
+
+// parseChallenges splits WWW-Authenticate headers into their challenges,
+// e.g. `Digest realm="x", qop="auth", nonce="abc", Basic realm="x"`
+func parseChallenges(headers []string) []challenge {
+	var challenges []challenge
+	for _, header := range headers {
+		s := header
+		var current *challenge
+		for {
+			s = strings.TrimLeft(s, " \t,")
+			if s == "" {
+				break
+			}
+
+			// A token not followed by "=" starts a new challenge
+			token := s
+			if i := strings.IndexAny(s, " \t,="); i >= 0 {
+				token = s[:i]
+			}
+			rest := strings.TrimLeft(s[len(token):], " \t")
+			if !strings.HasPrefix(rest, "=") {
+				challenges = append(challenges, challenge{scheme: strings.ToLower(token), params: make(map[string]string)})
+				current = &challenges[len(challenges)-1]
+				s = rest
+				continue
+			}
+
+			// key=value or key="quoted value"
+			value, remaining := readParamValue(strings.TrimLeft(rest[1:], " \t"))
+			if current != nil {
+				current.params[strings.ToLower(token)] = value
+			}
+			s = remaining
+		}
+	}
+	return challenges
+}
+
+// readParamValue reads a token or quoted string and returns it with the rest of s
+func readParamValue(s string) (string, string) {
+	if !strings.HasPrefix(s, `"`) {
+		if i := strings.IndexAny(s, " \t,"); i >= 0 {
+			return s[:i], s[i:]
+		}
+		return s, ""
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// basicAuth builds a Basic Authorization header value
+func basicAuth(creds credentials) string {
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth(creds.user, creds.password)
+	return req.Header.Get("Authorization")
+}
+
+// digestAuth answers RFC 7616 Digest challenges. It remembers the nonce
+// count per server nonce so repeated requests stay valid.
+type digestAuth struct {
+	mu     sync.Mutex
+	counts map[string]int
+	// cnonce returns the client nonce; nil means 16 random bytes
+	cnonce func() (string, error)
+}
+
+// authorize builds the Digest Authorization header value for req
+func (d *digestAuth) authorize(req *http.Request, c challenge, creds credentials) (string, error) {
+	algorithm := c.params["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	case "SHA-512-256":
+		newHash = sha512.New512_256
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %s", algorithm)
+	}
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	realm, nonce := c.params["realm"], c.params["nonce"]
+	qop := ""
+	if offered := c.params["qop"]; offered != "" {
+		for _, q := range strings.Split(offered, ",") {
+			if strings.TrimSpace(q) == "auth" {
+				qop = "auth"
+			}
+		}
+		if qop == "" {
+			return "", fmt.Errorf("unsupported digest qop %q", offered)
+		}
+	}
+
+	newCnonce := d.cnonce
+	if newCnonce == nil {
+		newCnonce = func() (string, error) { return randomHex(16) }
+	}
+	cnonce, err := newCnonce()
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	if d.counts == nil {
+		d.counts = make(map[string]int)
+	}
+	d.counts[nonce]++
+	nc := fmt.Sprintf("%08x", d.counts[nonce])
+	d.mu.Unlock()
+
+	uri := req.URL.RequestURI()
+	ha1 := h(creds.user + ":" + realm + ":" + creds.password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(req.Method + ":" + uri)
+
+	var response string
+	if qop == "" {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":" + qop + ":" + ha2)
+	}
+
+	// userhash hides the user name from anyone watching the connection
+	username := creds.user
+	userhash := strings.EqualFold(c.params["userhash"], "true")
+	if userhash {
+		username = h(creds.user + ":" + realm)
+	}
+
+	parts := []string{
+		fmt.Sprintf("username=%q", username),
+		fmt.Sprintf("realm=%q", realm),
+		fmt.Sprintf("nonce=%q", nonce),
+		fmt.Sprintf("uri=%q", uri),
+		"algorithm=" + algorithm,
+		fmt.Sprintf("response=%q", response),
+	}
+	if opaque, ok := c.params["opaque"]; ok {
+		parts = append(parts, fmt.Sprintf("opaque=%q", opaque))
+	}
+	if qop != "" {
+		parts = append(parts, "qop="+qop, "nc="+nc, fmt.Sprintf("cnonce=%q", cnonce))
+	}
+	if userhash {
+		parts = append(parts, "userhash=true")
+	}
+	return "Digest " + strings.Join(parts, ", "), nil
+}
+
+// digestStrength ranks the algorithm of a Digest challenge, higher being
+// stronger (RFC 7616 section 3.7); unsupported ones rank 0
+func digestStrength(c challenge) int {
+	switch strings.TrimSuffix(strings.ToUpper(c.params["algorithm"]), "-SESS") {
+	case "SHA-512-256":
+		return 3
+	case "SHA-256":
+		return 2
+	case "", "MD5":
+		return 1
+	}
+	return 0
+}
+
+// answer picks the strongest challenge we support and returns the
+// Authorization header value for it: Digest with the strongest algorithm,
+// then Basic
+func (c *Client) answer(req *http.Request, headers []string, creds credentials) (string, error) {
+	var digests []challenge
+	basic := false
+	for _, ch := range parseChallenges(headers) {
+		switch ch.scheme {
+		case "digest":
+			digests = append(digests, ch)
+		case "basic":
+			basic = true
+		}
+	}
+
+	// Servers offering several algorithms list them in their own order
+	slices.SortStableFunc(digests, func(a, b challenge) int {
+		return digestStrength(b) - digestStrength(a)
+	})
+	var lastErr error
+	for _, ch := range digests {
+		auth, err := c.digest.authorize(req, ch, creds)
+		if err == nil {
+			return auth, nil
+		}
+		lastErr = err
+	}
+	if basic {
+		return basicAuth(creds), nil
+	}
+	if lastErr != nil {
+		return "", lastErr
+	}
+	return "", fmt.Errorf("no supported authentication scheme offered")
+}
+
+// randomHex returns n random bytes as hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,199 @@
+package httpClient
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuthNoChallengeHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	os.WriteFile(netrc, []byte("machine netrc.test login bob password hunter2\n"), 0600)
+
+	var resolve []string
+	for _, host := range []string{"start.test", "other.test", "netrc.test"} {
+		resolve = append(resolve, host+":"+port+":127.0.0.1")
+	}
+	c, err := New(Options{
+		User:            "alice",
+		Password:        "secret",
+		AuthNoChallenge: true,
+		NetrcFile:       netrc,
+		Resolve:         resolve,
+		NoHSTS:          true,
+		NoCookies:       true,
+		NoProxy:         true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AddStartURL("http://Start.test:" + port + "/file")
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"start.test", basicAuth(credentials{user: "alice", password: "secret"})},
+		{"other.test", ""},
+		{"netrc.test", basicAuth(credentials{user: "bob", password: "hunter2"})},
+	}
+	for _, tt := range tests {
+		if got := getBody(t, c, "http://"+tt.host+":"+port+"/"); got != tt.want {
+			t.Errorf("%s: Authorization %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestDigestKnownAnswers(t *testing.T) {
+	// Inputs from the examples in RFC 7616 section 3.9
+	mufasa := credentials{user: "Mufasa", password: "Circle of Life"}
+	mufasaChallenge := map[string]string{
+		"realm":  "http-auth@example.org",
+		"qop":    "auth, auth-int",
+		"nonce":  "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+		"opaque": "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS",
+	}
+	const mufasaCnonce = "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+
+	jason := credentials{user: "Jäsøn Doe", password: "Secret, or not?"}
+	jasonChallenge := map[string]string{
+		"realm":    "api@example.org",
+		"qop":      "auth",
+		"nonce":    "5TsQWLVdgBdmrQ0XsxbDODV+57QdFR34I9HAbC/RVvkK",
+		"opaque":   "HRPCssKJSGjCrkzDg8OhwpzCiGPChXYjwrI2QmXDnsOS",
+		"userhash": "true",
+	}
+	const jasonCnonce = "NTg6RKcb9boFIAS3KrFK9BGeh+iDa/sm6jUMp2wds69v"
+
+	tests := []struct {
+		algorithm string
+		creds     credentials
+		challenge map[string]string
+		cnonce    string
+		uri       string
+		// username is what the header must carry; a hash with userhash
+		username string
+		response string
+	}{
+		// The MD5 and SHA-256 responses are the ones printed in the RFC
+		{"MD5", mufasa, mufasaChallenge, mufasaCnonce, "/dir/index.html",
+			"Mufasa", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", mufasa, mufasaChallenge, mufasaCnonce, "/dir/index.html",
+			"Mufasa", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+		// The -sess, SHA-512-256 and userhash answers were computed
+		// separately with Python's hashlib from the same inputs
+		{"MD5-sess", mufasa, mufasaChallenge, mufasaCnonce, "/dir/index.html",
+			"Mufasa", "e783283f46242139c486a698fec7211d"},
+		{"SHA-256-sess", mufasa, mufasaChallenge, mufasaCnonce, "/dir/index.html",
+			"Mufasa", "2fd51b3a77ad75bad6afad6003e818d767133c46d9e2749e7f5232ae1ea3efd7"},
+		{"SHA-512-256", jason, jasonChallenge, jasonCnonce, "/doc/index.html",
+			"793263caabb707a56211940d90411ea4a575adeccb7e360aeb624ed06ece9b0b",
+			"93308f41873a77f41ea3d87886878276f1a92271362e72275c3d3a38cf9f5fd6"},
+		{"MD5", mufasa, withParam(mufasaChallenge, "userhash", "true"), mufasaCnonce, "/dir/index.html",
+			"4238f3a16167373febb9bc4d43db9cc4", "8ca523f5e9506fed4657c9700eebdbec"},
+	}
+	for _, tt := range tests {
+		d := &digestAuth{cnonce: func() (string, error) { return tt.cnonce, nil }}
+		req := httptest.NewRequest(http.MethodGet, "http://example.org"+tt.uri, nil)
+		params := withParam(tt.challenge, "algorithm", tt.algorithm)
+
+		auth, err := d.authorize(req, challenge{scheme: "digest", params: params}, tt.creds)
+		if err != nil {
+			t.Errorf("%s: %v", tt.algorithm, err)
+			continue
+		}
+		answers := parseChallenges([]string{auth})
+		if len(answers) != 1 || answers[0].scheme != "digest" {
+			t.Errorf("%s: cannot parse %q", tt.algorithm, auth)
+			continue
+		}
+		got := answers[0].params
+		want := map[string]string{
+			"username":  tt.username,
+			"realm":     tt.challenge["realm"],
+			"nonce":     tt.challenge["nonce"],
+			"uri":       tt.uri,
+			"algorithm": tt.algorithm,
+			"response":  tt.response,
+			"opaque":    tt.challenge["opaque"],
+			"qop":       "auth",
+			"nc":        "00000001",
+			"cnonce":    tt.cnonce,
+		}
+		if tt.challenge["userhash"] == "true" {
+			want["userhash"] = "true"
+		}
+		for name, value := range want {
+			if got[name] != value {
+				t.Errorf("%s: %s = %q, want %q", tt.algorithm, name, got[name], value)
+			}
+		}
+	}
+}
+
+func TestDigestNonceCount(t *testing.T) {
+	d := &digestAuth{}
+	ch := challenge{scheme: "digest", params: map[string]string{"realm": "r", "nonce": "n", "qop": "auth"}}
+	req := httptest.NewRequest(http.MethodGet, "http://example.org/", nil)
+	for _, want := range []string{"00000001", "00000002"} {
+		auth, err := d.authorize(req, ch, credentials{user: "u", password: "p"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := parseChallenges([]string{auth})[0].params["nc"]; got != want {
+			t.Errorf("nc = %q, want %q", got, want)
+		}
+	}
+}
+
+// withParam returns a copy of params with name set to value
+func withParam(params map[string]string, name, value string) map[string]string {
+	out := map[string]string{name: value}
+	for k, v := range params {
+		if k != name {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func TestAnswerPicksStrongest(t *testing.T) {
+	c := newTestClient(t, Options{})
+	creds := credentials{user: "u", password: "p"}
+	tests := []struct {
+		headers []string
+		// want is the start of the answer and the algorithm it uses, if any
+		want, algorithm string
+	}{
+		{[]string{`Digest realm="r", nonce="n", algorithm=MD5, qop="auth"`, `Digest realm="r", nonce="n", algorithm=SHA-256, qop="auth"`}, "Digest ", "SHA-256"},
+		{[]string{`Digest realm="r", nonce="n", algorithm=SHA-256`, `Digest realm="r", nonce="n", algorithm=SHA-512-256-sess, qop="auth"`, `Digest realm="r", nonce="n"`}, "Digest ", "SHA-512-256-sess"},
+		{[]string{`Basic realm="r"`, `Digest realm="r", nonce="n"`}, "Digest ", "MD5"},
+		{[]string{`Digest realm="r", nonce="n", algorithm=UNKNOWN`, `Basic realm="r"`}, "Basic ", ""},
+		{[]string{`Digest realm="r", nonce="n", algorithm=UNKNOWN`}, "", ""},
+		{[]string{`Bearer realm="r"`}, "", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		got, err := c.answer(req, tt.headers, creds)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%q: answered %q, want an error", tt.headers, got)
+			}
+			continue
+		}
+		if err != nil || !strings.HasPrefix(got, tt.want) || (tt.algorithm != "" && !strings.Contains(got, "algorithm="+tt.algorithm+",")) {
+			t.Errorf("%q: answered %q, %v; want %s with algorithm %s", tt.headers, got, err, tt.want, tt.algorithm)
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"wget/exitStatus"
+)
+
+// DefaultUserAgent is sent unless -U/--user-agent says otherwise
+const DefaultUserAgent = "Wget/1.0"
+
+// Options are the request settings shared by every download in a run
+type Options struct {
+	// Headers are extra "Name: value" headers from --header
+	Headers []string
+	// UserAgent replaces DefaultUserAgent when set (-U)
+	UserAgent string
+	// Referer is sent as the Referer header (--referer)
+	Referer string
+	// AcceptLanguage is sent as the Accept-Language header (--accept-language)
+	AcceptLanguage string
+
+	// User and Password authenticate with the hosts the user asked for
+	// (--user/--password or --http-user/--http-password)
+	User, Password string
+	// AuthNoChallenge sends Basic credentials without waiting for a 401 to
+	// the hosts of the start URLs and those in .netrc (--auth-no-challenge)
+	AuthNoChallenge bool
+	// NetrcFile is read for per-host credentials; empty means ~/.netrc
+	NetrcFile string
+
+	// BearerToken is sent as "Authorization: Bearer" (--bearer-token)
+	BearerToken string
+	// OAuthTokenURL, ClientID and ClientSecret fetch bearer tokens with the
+	// OAuth2 client credentials grant (--oauth-token-url, --client-id,
+	// --client-secret)
+	OAuthTokenURL, ClientID, ClientSecret string
+
+	// LoadCookies and SaveCookies are cookies.txt files read at the start
+	// and written by SaveCookies (--load-cookies, --save-cookies)
+	LoadCookies, SaveCookies string
+	// KeepSessionCookies saves cookies that have no expiry too
+	// (--keep-session-cookies)
+	KeepSessionCookies bool
+	// NoCookies neither sends nor stores cookies (--no-cookies)
+	NoCookies bool
+
+	// Proxy replaces http_proxy and https_proxy from the environment
+	// (--proxy); socks5:// proxies are supported too
+	Proxy string
+	// ProxyUser and ProxyPassword authenticate with the proxy
+	// (--proxy-user, --proxy-password)
+	ProxyUser, ProxyPassword string
+	// ProxyPAC is a proxy auto-config file or URL whose FindProxyForURL
+	// picks the proxy for each request (--proxy-pac)
+	ProxyPAC string
+	// NoProxy connects directly even when a proxy is configured (--no-proxy)
+	NoProxy bool
+
+	// CACertificate and CADirectory hold extra trusted CAs
+	// (--ca-certificate, --ca-directory)
+	CACertificate, CADirectory string
+	// Certificate and PrivateKey authenticate the client over TLS
+	// (--certificate, --private-key)
+	Certificate, PrivateKey string
+	// NoCheckCertificate skips server certificate verification, so no HSTS
+	// policies are recorded either (--no-check-certificate)
+	NoCheckCertificate bool
+	// SecureProtocol is auto, TLSv1_2 or TLSv1_3 (--secure-protocol)
+	SecureProtocol string
+	// PinnedPubKey lists sha256//<base64> hashes or a key file the server's
+	// public key must match (--pinnedpubkey)
+	PinnedPubKey string
+
+	// HSTSFile is the HSTS database; empty means ~/.wget-hsts (--hsts-file)
+	HSTSFile string
+	// NoHSTS neither applies nor records HSTS policies (--no-hsts)
+	NoHSTS bool
+
+	// DNSTimeout, ConnectTimeout and ReadTimeout limit name lookups,
+	// connecting and the wait between reads; 0 means no limit
+	// (--dns-timeout, --connect-timeout, --read-timeout)
+	DNSTimeout, ConnectTimeout, ReadTimeout time.Duration
+
+	// Family is "4" or "6" to connect over that IP version only (-4, -6)
+	Family string
+	// PreferFamily is IPv4, IPv6 or none (--prefer-family)
+	PreferFamily string
+	// Resolve lists "host:port:addr[,addr]" overrides of DNS (--resolve)
+	Resolve []string
+	// BindAddress is the local address connections are made from (--bind-address)
+	BindAddress string
+	// DNSServers is a comma-separated list of resolvers to ask instead of
+	// the system ones (--dns-servers)
+	DNSServers string
+	// UnixSocket is a socket path every connection is made to instead of
+	// the host in the URL (--unix-socket)
+	UnixSocket string
+
+	// Compression is auto, gzip or none (--compression); empty means auto
+	Compression string
+	// MaxExpansion is the largest decompressed-to-received ratio accepted,
+	// 0 for no limit (--max-expansion-ratio)
+	MaxExpansion int64
+}
+
+// Client sends every request of a run. It adds the configured headers,
+// keeps the cookie jar, answers Basic and Digest challenges, and keeps
+// --header values and credentials to the host they were given for when a
+// redirect leads somewhere else.
+type Client struct {
+	http      *http.Client
+	transport *http.Transport
+	headers   http.Header
+	userAgent string
+	referer   string
+	language  string
+
+	creds       credentials
+	noChallenge bool
+	netrc       *netrc
+	digest      digestAuth
+	tokens      *tokenSource
+	// startHosts are the hosts of the URLs the user asked for, the only
+	// ones --auth-no-challenge sends the command-line credentials to
+	startHosts sync.Map
+
+	jar         *cookieJar
+	saveCookies string
+	keepSession bool
+
+	hsts *hstsStore
+
+	compression  string
+	maxExpansion int64
+}
+
+// New builds a Client from opts
+func New(opts Options) (*Client, error) {
+	c := &Client{
+		headers:   make(http.Header),
+		userAgent: DefaultUserAgent,
+		referer:   opts.Referer,
+		language:  opts.AcceptLanguage,
+
+		creds:       credentials{user: opts.User, password: opts.Password},
+		noChallenge: opts.AuthNoChallenge,
+		netrc:       loadNetrc(opts.NetrcFile),
+
+		compression:  opts.Compression,
+		maxExpansion: opts.MaxExpansion,
+	}
+	if c.compression == "" {
+		c.compression = "auto"
+	}
+	if err := checkCompression(c.compression); err != nil {
+		return nil, err
+	}
+	if opts.UserAgent != "" {
+		c.userAgent = opts.UserAgent
+	}
+
+	for _, header := range opts.Headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header %q (expected \"Name: value\")", header)
+		}
+		c.headers.Add(name, strings.TrimSpace(value))
+	}
+
+	// One transport for every request, so connections and proxy settings are shared
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	c.transport = http.DefaultTransport.(*http.Transport).Clone()
+	c.transport.Proxy = nil
+	c.transport.TLSClientConfig = tlsConfig
+	// Content-Encoding is negotiated and decoded by the client itself
+	c.transport.DisableCompression = true
+	dialer, err := newDialer(opts)
+	if err != nil {
+		return nil, err
+	}
+	c.transport.DialContext = dialer.DialContext
+
+	// A PAC file is fetched directly, with the TLS and connection settings
+	pacClient := &http.Client{Transport: c.transport.Clone(), Timeout: 30 * time.Second}
+	proxy, err := proxyFunc(opts, pacClient, dialer)
+	if err != nil {
+		return nil, err
+	}
+	if opts.UnixSocket != "" {
+		// The socket is the server; a proxy can't be in between
+		proxy = nil
+	}
+	c.transport.Proxy = proxy
+
+	c.http = &http.Client{Transport: c.transport, CheckRedirect: c.checkRedirect}
+	if !opts.NoHSTS {
+		if c.hsts, err = loadHSTS(opts.HSTSFile); err != nil {
+			return nil, err
+		}
+		c.hsts.readOnly = opts.NoCheckCertificate
+	}
+	if !opts.NoCookies {
+		c.jar = newCookieJar()
+		c.http.Jar = c.jar
+		c.saveCookies, c.keepSession = opts.SaveCookies, opts.KeepSessionCookies
+		if opts.LoadCookies != "" {
+			if err := c.jar.load(opts.LoadCookies); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if opts.OAuthTokenURL != "" && opts.ClientID == "" {
+		return nil, fmt.Errorf("--oauth-token-url needs --client-id")
+	}
+	if (opts.ClientID != "" || opts.ClientSecret != "") && opts.OAuthTokenURL == "" {
+		return nil, fmt.Errorf("--client-id and --client-secret need --oauth-token-url")
+	}
+	if opts.BearerToken != "" || opts.OAuthTokenURL != "" {
+		c.tokens = &tokenSource{
+			token:        opts.BearerToken,
+			tokenURL:     opts.OAuthTokenURL,
+			clientID:     opts.ClientID,
+			clientSecret: opts.ClientSecret,
+			http:         &http.Client{Transport: c.transport},
+		}
+	}
+	return c, nil
+}
+
+// Transport returns the transport shared by every request of the run
+func (c *Client) Transport() http.RoundTripper {
+	return c.transport
+}
+
+// Get sends a GET request for url
+func (c *Client) Get(url string) (*http.Response, error) {
+	return c.GetContext(context.Background(), url)
+}
+
+// GetContext sends a GET request for url that is cancelled with ctx
+func (c *Client) GetContext(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do adds the configured headers to req and sends it, over https if the
+// host has an HSTS policy. A 401 response is answered once with the
+// credentials for the host that sent it.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if c.hsts != nil {
+		c.hsts.upgrade(req.URL)
+	}
+	c.setCommonHeaders(req)
+	for name, values := range c.headers {
+		req.Header[name] = append([]string(nil), values...)
+	}
+
+	if c.tokens != nil {
+		return c.doBearer(req)
+	}
+
+	if c.noChallenge {
+		host := req.URL.Hostname()
+		_, started := c.startHosts.Load(strings.ToLower(host))
+		if creds, ok := c.credentialsFor(host, started); ok {
+			req.Header.Set("Authorization", basicAuth(creds))
+		}
+	}
+
+	resp, err := c.send(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// The challenge may come from a redirect target, which only gets
+	// credentials of its own from .netrc
+	final := resp.Request
+	creds, ok := c.credentialsFor(final.URL.Hostname(), final.URL.Host == req.URL.Host)
+	if !ok {
+		return resp, nil
+	}
+	auth, err := c.answer(final, resp.Header.Values("WWW-Authenticate"), creds)
+	if err != nil || auth == final.Header.Get("Authorization") {
+		// Nothing new to try
+		return resp, nil
+	}
+
+	retry := final.Clone(final.Context())
+	if final.GetBody != nil {
+		if retry.Body, err = final.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	retry.Header.Set("Authorization", auth)
+	resp.Body.Close()
+	return c.send(retry)
+}
+
+// doBearer sends req with a bearer token. If the server rejects the token
+// it is refreshed and the request sent once more.
+func (c *Client) doBearer(req *http.Request) (*http.Response, error) {
+	token, err := c.tokens.get()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.send(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !c.tokens.canRefresh() {
+		return resp, err
+	}
+	// A 401 from a redirect target isn't about our token
+	if resp.Request.URL.Host != req.URL.Host {
+		return resp, nil
+	}
+
+	token, err = c.tokens.refresh(token)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+token)
+	resp.Body.Close()
+	return c.send(retry)
+}
+
+// send sends req, records HSTS policies, decompresses the body and
+// explains TLS failures and malformed responses
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.http.Do(req)
+	if err == nil && c.hsts != nil {
+		c.hsts.record(resp)
+	}
+	if err == nil {
+		c.decode(resp)
+	}
+	if isMalformed(err) {
+		err = exitStatus.Wrap(exitStatus.Protocol, fmt.Errorf("%w: %v", ErrMalformedResponse, err))
+	}
+	return resp, diagnoseTLS(err, req.URL.Hostname())
+}
+
+// ErrMalformedResponse is returned when the server's answer isn't valid HTTP
+var ErrMalformedResponse = errors.New("malformed response")
+
+// ErrTooManyRedirects is returned when a request is redirected more than
+// maxRedirects times
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// isMalformed reports whether err means the response couldn't be parsed.
+// net/http reports a bad status line only as text, so that one is
+// recognized by its message; bad header lines have a type.
+func isMalformed(err error) bool {
+	if err == nil {
+		return false
+	}
+	var headerErr textproto.ProtocolError
+	var protocolErr *http.ProtocolError
+	return errors.As(err, &headerErr) || errors.As(err, &protocolErr) ||
+		strings.Contains(err.Error(), "malformed HTTP")
+}
+
+// Save writes what the run learned: the cookie jar to the --save-cookies
+// file, if any, and the HSTS database
+func (c *Client) Save() error {
+	var errs []error
+	if c.jar != nil && c.saveCookies != "" {
+		errs = append(errs, c.jar.save(c.saveCookies, c.keepSession))
+	}
+	if c.hsts != nil {
+		errs = append(errs, c.hsts.save())
+	}
+	return errors.Join(errs...)
+}
+
+// AddStartURL records the host of a URL the user asked for. With
+// --auth-no-challenge the command-line credentials are sent unasked to
+// these hosts only, not to the other hosts a mirror or a redirect reaches.
+func (c *Client) AddStartURL(rawURL string) {
+	if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+		c.startHosts.Store(strings.ToLower(u.Hostname()), true)
+	}
+}
+
+// credentialsFor returns the user name and password for host. The ones given
+// on the command line only go to hosts the user asked for; .netrc entries
+// name their own hosts.
+func (c *Client) credentialsFor(host string, requested bool) (credentials, bool) {
+	if requested && c.creds.user != "" {
+		return c.creds, true
+	}
+	if entry, ok := c.netrc.lookup(host); ok && entry.login != "" {
+		return credentials{user: entry.login, password: entry.password}, true
+	}
+	return credentials{}, false
+}
+
+// setCommonHeaders sets the headers that go to every host
+func (c *Client) setCommonHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", c.userAgent)
+	if encodings, ok := acceptEncoding[c.compression]; ok {
+		req.Header.Set("Accept-Encoding", encodings)
+	} else {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
+	if c.referer != "" {
+		req.Header.Set("Referer", c.referer)
+	}
+	if c.language != "" {
+		req.Header.Set("Accept-Language", c.language)
+	}
+}
+
+// maxRedirects is how many redirects wget follows for one request
+const maxRedirects = 20
+
+// checkRedirect follows up to maxRedirects redirects, applying HSTS to each
+// hop. net/http copies the original headers onto each redirect; custom
+// --header values and credentials are removed again when the redirect goes
+// to a different host.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return exitStatus.Wrap(exitStatus.Protocol, fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, len(via)))
+	}
+	if c.hsts != nil {
+		c.hsts.record(req.Response)
+		c.hsts.upgrade(req.URL)
+	}
+	if req.URL.Host != via[0].URL.Host {
+		for name := range c.headers {
+			req.Header.Del(name)
+		}
+		req.Header.Del("Authorization")
+		c.setCommonHeaders(req)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package httpClient
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"wget/exitStatus"
+)
+
+func TestTooManyRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(t, Options{}).Get(server.URL)
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("error %v, want ErrTooManyRedirects", err)
+	}
+	if code := exitStatus.Of(err); code != exitStatus.Protocol {
+		t.Errorf("exit status %d, want %d", code, exitStatus.Protocol)
+	}
+}
+
+func TestMalformedResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{"status line", "HTTP/1.1 two hundred OK\r\n\r\n"},
+		{"not HTTP", "SSH-2.0-OpenSSH_9.6\r\n"},
+		{"header line", "HTTP/1.1 200 OK\r\nno colon here\r\n\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer listener.Close()
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				// Read the request before answering, so it's not reset
+				conn.Read(make([]byte, 4096))
+				io.WriteString(conn, tt.response)
+			}()
+
+			_, err = newTestClient(t, Options{}).Get("http://" + listener.Addr().String() + "/")
+			if !errors.Is(err, ErrMalformedResponse) {
+				t.Fatalf("error %v, want ErrMalformedResponse", err)
+			}
+			if code := exitStatus.Of(err); code != exitStatus.Protocol {
+				t.Errorf("exit status %d, want %d", code, exitStatus.Protocol)
+			}
+		})
+	}
+}
+
+func TestNetworkErrorIsNotMalformed(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	_, err = newTestClient(t, Options{}).Get("http://" + addr + "/")
+	if errors.Is(err, ErrMalformedResponse) {
+		t.Fatalf("refused connection reported as malformed: %v", err)
+	}
+	if code := exitStatus.Of(err); code != exitStatus.Network {
+		t.Errorf("exit status %d, want %d (%v)", code, exitStatus.Network, err)
+	}
+}
+
+func TestTLSAlert(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	_, err := newTestClient(t, Options{NoCheckCertificate: true}).Get(server.URL)
+	if code := exitStatus.Of(err); code != exitStatus.SSL {
+		t.Errorf("exit status %d, want %d (%v)", code, exitStatus.SSL, err)
+	}
+}
+
+func TestRedirectHeaders(t *testing.T) {
+	// target reports the headers that reached it
+	target := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "token=%q auth=%q agent=%q", r.Header.Get("X-Token"), r.Header.Get("Authorization"), r.Header.Get("User-Agent"))
+	})
+	other := httptest.NewServer(target)
+	defer other.Close()
+	_, otherPort, _ := net.SplitHostPort(other.Listener.Addr().String())
+
+	mux := http.NewServeMux()
+	mux.Handle("/target", target)
+	mux.HandleFunc("/same", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/target", http.StatusFound)
+	})
+	mux.HandleFunc("/cross", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+otherPort+"/target", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		opts Options
+		path string
+		want string
+	}{
+		{"header on the same host", Options{Headers: []string{"X-Token: secret"}}, "/same",
+			`token="secret" auth="" agent="` + DefaultUserAgent + `"`},
+		{"header on another host", Options{Headers: []string{"X-Token: secret"}}, "/cross",
+			`token="" auth="" agent="` + DefaultUserAgent + `"`},
+		{"credentials on the same host", Options{User: "u", Password: "p", AuthNoChallenge: true}, "/same",
+			`token="" auth="Basic dTpw" agent="` + DefaultUserAgent + `"`},
+		{"credentials on another host", Options{User: "u", Password: "p", AuthNoChallenge: true}, "/cross",
+			`token="" auth="" agent="` + DefaultUserAgent + `"`},
+		{"Authorization header on another host", Options{Headers: []string{"Authorization: Bearer t"}, UserAgent: "custom"}, "/cross",
+			`token="" auth="" agent="custom"`},
+	}
+	for _, tt := range tests {
+		c := newTestClient(t, tt.opts)
+		c.AddStartURL(server.URL)
+		if got := getBody(t, c, server.URL+tt.path); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRedirectMethod(t *testing.T) {
+	// target reports the method, body and Content-Type that reached it
+	target := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %q %q", r.Method, body, r.Header.Get("Content-Type"))
+	})
+	other := httptest.NewServer(target)
+	defer other.Close()
+	_, otherPort, _ := net.SplitHostPort(other.Listener.Addr().String())
+
+	mux := http.NewServeMux()
+	mux.Handle("/target", target)
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(r.URL.Query().Get("code"))
+		location := "/target"
+		if r.URL.Query().Has("cross") {
+			location = "http://localhost:" + otherPort + "/target"
+		}
+		http.Redirect(w, r, location, code)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	bodyFile := filepath.Join(t.TempDir(), "body.json")
+	os.WriteFile(bodyFile, []byte(`{"a":1}`), 0644)
+
+	const form = `"a=1" "application/x-www-form-urlencoded"`
+	tests := []struct {
+		code           int
+		method         string
+		data, file     string
+		same, crossing string
+	}{
+		{301, "POST", "a=1", "", `GET "" ""`, `GET "" ""`},
+		{302, "POST", "a=1", "", `GET "" ""`, `GET "" ""`},
+		{303, "POST", "a=1", "", `GET "" ""`, `GET "" ""`},
+		{303, "PUT", "a=1", "", `GET "" ""`, `GET "" ""`},
+		{307, "POST", "a=1", "", "POST " + form, "POST " + form},
+		{308, "POST", "a=1", "", "POST " + form, "POST " + form},
+		{307, "PUT", "", bodyFile, `PUT "{\"a\":1}" "application/json"`, `PUT "{\"a\":1}" "application/json"`},
+		{308, "DELETE", "", "", `DELETE "" ""`, `DELETE "" ""`},
+	}
+	c := newTestClient(t, Options{})
+	for _, tt := range tests {
+		for _, cross := range []bool{false, true} {
+			url := server.URL + "/redirect?code=" + strconv.Itoa(tt.code)
+			want := tt.same
+			if cross {
+				url += "&cross"
+				want = tt.crossing
+			}
+			req, err := NewRequest(tt.method, url, tt.data, tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Body != nil && req.GetBody == nil {
+				t.Fatalf("%s %d: NewRequest left GetBody unset, so the body can't be resent", tt.method, tt.code)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != want {
+				t.Errorf("%s %d (cross-host %v): target got %s, want %s", tt.method, tt.code, cross, body, want)
+			}
+		}
+	}
+}
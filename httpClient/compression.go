@@ -0,0 +1,191 @@
+package httpClient
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"wget/exitStatus"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultMaxExpansion is the largest decompressed-to-received size ratio
+// accepted before a response is treated as a decompression bomb
+const DefaultMaxExpansion = 1000
+
+// bombThreshold is how much a body may expand before the ratio is checked,
+// so small well-compressed responses are never refused
+const bombThreshold = 1 << 20
+
+// acceptEncoding is what each --compression mode advertises
+var acceptEncoding = map[string]string{
+	"auto": "gzip, deflate, br, zstd",
+	"gzip": "gzip",
+}
+
+// compressedTypes are Content-Types whose body is a compressed file in its
+// own right. Servers often send such files with "Content-Encoding: gzip" as
+// well; the file is saved as it was served instead of unpacked.
+var compressedTypes = map[string]bool{
+	"application/gzip":            true,
+	"application/x-gzip":          true,
+	"application/x-gunzip":        true,
+	"application/gzipped":         true,
+	"application/gzip-compressed": true,
+	"application/x-compress":      true,
+	"application/x-compressed":    true,
+	"application/zstd":            true,
+	"application/x-brotli":        true,
+	"gzip/document":               true,
+}
+
+// checkCompression validates a --compression mode
+func checkCompression(mode string) error {
+	switch mode {
+	case "auto", "gzip", "none":
+		return nil
+	}
+	return fmt.Errorf("invalid --compression %q (expected auto, gzip or none)", mode)
+}
+
+// decode replaces the body of a compressed response with one that
+// decompresses while it is read. Content-Length stays the size on the wire,
+// which is what progress is measured in. Encodings we don't know, and
+// compressed files served with a Content-Encoding, are left as they are.
+func (c *Client) decode(resp *http.Response) {
+	if c.compression == "none" || resp.Body == nil {
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); compressedTypes[mediaType] {
+		return
+	}
+	var encodings []string
+	for _, encoding := range strings.Split(resp.Header.Get("Content-Encoding"), ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		switch encoding {
+		case "", "identity":
+		case "gzip", "x-gzip", "deflate", "br", "zstd":
+			encodings = append(encodings, encoding)
+		default:
+			return
+		}
+	}
+	if len(encodings) == 0 {
+		return
+	}
+
+	resp.Body = &decodedBody{
+		wire:      &countingReader{r: resp.Body},
+		closer:    resp.Body,
+		encodings: encodings,
+		maxExpand: c.maxExpansion,
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Uncompressed = true
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// decodedBody decompresses a response body. The decoders are set up on
+// the first read, so empty bodies with a Content-Encoding are fine.
+type decodedBody struct {
+	wire      *countingReader
+	closer    io.Closer
+	encodings []string
+	maxExpand int64
+
+	r       io.Reader
+	closers []io.Closer
+	decoded int64
+}
+
+// WireBytes is the number of compressed bytes received so far
+func (b *decodedBody) WireBytes() int64 {
+	return b.wire.n
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.r == nil {
+		if err := b.open(); err != nil {
+			return 0, exitStatus.Wrap(exitStatus.Protocol, fmt.Errorf("cannot decompress response: %v", err))
+		}
+	}
+
+	n, err := b.r.Read(p)
+	b.decoded += int64(n)
+	if b.maxExpand > 0 && b.decoded > bombThreshold && b.decoded/b.maxExpand > b.wire.n {
+		return n, exitStatus.Wrap(exitStatus.Protocol, fmt.Errorf(
+			"decompression bomb: %d bytes received expanded to more than %d bytes (over %dx); raise --max-expansion-ratio if this is expected",
+			b.wire.n, b.decoded, b.maxExpand))
+	}
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("cannot decompress response: %w", err)
+	}
+	return n, err
+}
+
+// open stacks the decoders; encodings are undone in reverse order
+func (b *decodedBody) open() error {
+	var r io.Reader = b.wire
+	for i := len(b.encodings) - 1; i >= 0; i-- {
+		switch b.encodings[i] {
+		case "gzip", "x-gzip":
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return err
+			}
+			b.closers = append(b.closers, gz)
+			r = gz
+		case "deflate":
+			r = newDeflateReader(r)
+		case "br":
+			r = brotli.NewReader(r)
+		case "zstd":
+			zr, err := zstd.NewReader(r)
+			if err != nil {
+				return err
+			}
+			b.closers = append(b.closers, zr.IOReadCloser())
+			r = zr
+		}
+	}
+	b.r = r
+	return nil
+}
+
+func (b *decodedBody) Close() error {
+	for _, closer := range b.closers {
+		closer.Close()
+	}
+	return b.closer.Close()
+}
+
+// newDeflateReader reads HTTP "deflate" bodies, which should be zlib
+// streams but are raw deflate data on some servers
+func newDeflateReader(r io.Reader) io.Reader {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if zr, err := zlib.NewReader(buffered); err == nil {
+			return zr
+		}
+	}
+	return flate.NewReader(buffered)
+}
@@ -0,0 +1,90 @@
+package httpClient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wget/exitStatus"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// serveGzip answers every request with body, sent with Content-Encoding
+// gzip and the given Content-Type
+func serveGzip(body []byte, contentType string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(body)
+	}))
+}
+
+func TestDecodeContentType(t *testing.T) {
+	plain := []byte("hello, world\n")
+	compressed := gzipped(t, plain)
+	tests := []struct {
+		contentType string
+		want        []byte
+	}{
+		{"text/plain; charset=utf-8", plain},
+		{"application/octet-stream", plain},
+		{"application/gzip", compressed},
+		{"application/x-gzip", compressed},
+		{"Application/X-Gzip; name=a.tar.gz", compressed},
+		{"application/zstd", compressed},
+	}
+	for _, tt := range tests {
+		server := serveGzip(compressed, tt.contentType)
+		c := newTestClient(t, Options{})
+		if got := getBody(t, c, server.URL); !bytes.Equal([]byte(got), tt.want) {
+			t.Errorf("Content-Type %q: body %q, want %q", tt.contentType, got, tt.want)
+		}
+		server.Close()
+	}
+}
+
+func TestDecodeExpansionLimit(t *testing.T) {
+	// 16 MiB of zeros compress to about 16 KiB, a ratio of about 1000
+	bomb := gzipped(t, make([]byte, 16<<20))
+	server := serveGzip(bomb, "text/plain")
+	defer server.Close()
+
+	tests := []struct {
+		maxExpansion int64
+		wantErr      bool
+	}{
+		{100, true},
+		{0, false},
+		{10000, false},
+		// Must not overflow into a negative limit
+		{math.MaxInt64, false},
+	}
+	for _, tt := range tests {
+		c := newTestClient(t, Options{MaxExpansion: tt.maxExpansion})
+		resp, err := c.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("--max-expansion-ratio %d: read %d bytes, error %v; want error %v", tt.maxExpansion, n, err, tt.wantErr)
+		}
+		if err != nil && exitStatus.Of(err) != exitStatus.Protocol {
+			t.Errorf("--max-expansion-ratio %d: exit status %d, want %d", tt.maxExpansion, exitStatus.Of(err), exitStatus.Protocol)
+		}
+	}
+}
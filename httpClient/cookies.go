@@ -0,0 +1,286 @@
+package httpClient
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wget/exitStatus"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// httpOnlyPrefix marks HttpOnly cookies in cookies.txt files (curl and wget
+// write it in front of the domain)
+const httpOnlyPrefix = "#HttpOnly_"
+
+// cookie is one stored cookie. A zero expires means a session cookie.
+type cookie struct {
+	domain   string
+	hostOnly bool
+	path     string
+	secure   bool
+	httpOnly bool
+	expires  time.Time
+	name     string
+	value    string
+	created  time.Time
+}
+
+// cookieJar keeps the cookies of a run. It implements http.CookieJar and
+// reads and writes the Netscape cookies.txt format.
+type cookieJar struct {
+	mu      sync.Mutex
+	cookies map[string]*cookie
+}
+
+// newCookieJar returns an empty jar
+func newCookieJar() *cookieJar {
+	return &cookieJar{cookies: make(map[string]*cookie)}
+}
+
+// key identifies a cookie; setting one with the same key replaces it
+func (c *cookie) key() string {
+	return c.domain + ";" + c.path + ";" + c.name
+}
+
+// expired reports whether a persistent cookie has run out
+func (c *cookie) expired(now time.Time) bool {
+	return !c.expires.IsZero() && !c.expires.After(now)
+}
+
+// matches reports whether c should be sent to u
+func (c *cookie) matches(u *url.URL, now time.Time) bool {
+	host := strings.ToLower(u.Hostname())
+	if c.hostOnly && host != c.domain {
+		return false
+	}
+	if !c.hostOnly && host != c.domain && !strings.HasSuffix(host, "."+c.domain) {
+		return false
+	}
+	if c.secure && u.Scheme != "https" {
+		return false
+	}
+	return pathMatches(u.EscapedPath(), c.path) && !c.expired(now)
+}
+
+// pathMatches implements the path-match rule of RFC 6265 section 5.1.4
+func pathMatches(requestPath, cookiePath string) bool {
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	if requestPath == cookiePath {
+		return true
+	}
+	return strings.HasPrefix(requestPath, cookiePath) &&
+		(strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/')
+}
+
+// defaultPath is the cookie path used when Set-Cookie doesn't give one
+func defaultPath(u *url.URL) string {
+	p := u.EscapedPath()
+	if p == "" || p[0] != '/' || strings.Count(p, "/") == 1 {
+		return "/"
+	}
+	return p[:strings.LastIndex(p, "/")]
+}
+
+// Cookies returns the cookies to send with a request to u
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	var matched []*cookie
+	for key, c := range j.cookies {
+		if c.expired(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		if c.matches(u, now) {
+			matched = append(matched, c)
+		}
+	}
+
+	// Longer paths first, then older cookies first (RFC 6265 section 5.4)
+	sort.Slice(matched, func(a, b int) bool {
+		if len(matched[a].path) != len(matched[b].path) {
+			return len(matched[a].path) > len(matched[b].path)
+		}
+		return matched[a].created.Before(matched[b].created)
+	})
+
+	cookies := make([]*http.Cookie, len(matched))
+	for i, c := range matched {
+		cookies[i] = &http.Cookie{Name: c.name, Value: c.value}
+	}
+	return cookies
+}
+
+// SetCookies stores the cookies set by a response from u. Cookies for
+// another domain or for a public suffix such as co.uk are ignored.
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	host := strings.ToLower(u.Hostname())
+	for _, hc := range cookies {
+		c := &cookie{
+			name:     hc.Name,
+			value:    hc.Value,
+			path:     hc.Path,
+			secure:   hc.Secure,
+			httpOnly: hc.HttpOnly,
+			created:  now,
+		}
+		domain, hostOnly, ok := cookieDomain(host, hc.Domain)
+		if !ok {
+			continue
+		}
+		c.domain, c.hostOnly = domain, hostOnly
+		if c.path == "" || c.path[0] != '/' {
+			c.path = defaultPath(u)
+		}
+
+		switch {
+		case hc.MaxAge < 0:
+			c.expires = now
+		case hc.MaxAge > 0:
+			c.expires = now.Add(time.Duration(hc.MaxAge) * time.Second)
+		case !hc.Expires.IsZero():
+			c.expires = hc.Expires
+		}
+		if c.expired(now) {
+			// Servers delete cookies by setting them in the past
+			delete(j.cookies, c.key())
+			continue
+		}
+		if old, ok := j.cookies[c.key()]; ok {
+			c.created = old.created
+		}
+		j.cookies[c.key()] = c
+	}
+}
+
+// cookieDomain works out which hosts a cookie from host applies to. A
+// Domain attribute must cover host and must not be a public suffix.
+func cookieDomain(host, attr string) (string, bool, bool) {
+	domain := strings.ToLower(strings.TrimPrefix(attr, "."))
+	if domain == "" || domain == host {
+		return host, domain == "", true
+	}
+	if net.ParseIP(host) != nil || !strings.HasSuffix(host, "."+domain) {
+		return "", false, false
+	}
+	if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain {
+		return "", false, false
+	}
+	return domain, false, true
+}
+
+// load reads a cookies.txt file. Lines with an expiry of 0 are session
+// cookies; expired cookies are dropped.
+func (j *cookieJar) load(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("cannot load cookies: %v", err))
+	}
+	defer f.Close()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		line = strings.TrimPrefix(line, httpOnlyPrefix)
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			return exitStatus.Wrap(exitStatus.Parse, fmt.Errorf("%s:%d: malformed cookie line", file, lineNo))
+		}
+		seconds, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return exitStatus.Wrap(exitStatus.Parse, fmt.Errorf("%s:%d: invalid expiry %q", file, lineNo, fields[4]))
+		}
+
+		c := &cookie{
+			domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			hostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			path:     fields[2],
+			secure:   strings.EqualFold(fields[3], "TRUE"),
+			httpOnly: httpOnly,
+			name:     fields[5],
+			value:    strings.Join(fields[6:], "\t"),
+			created:  now,
+		}
+		if seconds != 0 {
+			c.expires = time.Unix(seconds, 0)
+		}
+		if c.expired(now) {
+			continue
+		}
+		j.cookies[c.key()] = c
+	}
+	if err := scanner.Err(); err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("cannot load cookies: %v", err))
+	}
+	return nil
+}
+
+// save writes the jar to a cookies.txt file. Session cookies are written
+// with an expiry of 0 when keepSession is set and left out otherwise.
+func (j *cookieJar) save(file string, keepSession bool) error {
+	j.mu.Lock()
+	var lines []string
+	now := time.Now()
+	for _, c := range j.cookies {
+		if c.expired(now) || (c.expires.IsZero() && !keepSession) {
+			continue
+		}
+		domain := c.domain
+		if !c.hostOnly {
+			domain = "." + domain
+		}
+		if c.httpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if !c.expires.IsZero() {
+			expires = c.expires.Unix()
+		}
+		lines = append(lines, strings.Join([]string{
+			domain, strings.ToUpper(strconv.FormatBool(!c.hostOnly)), c.path,
+			strings.ToUpper(strconv.FormatBool(c.secure)), strconv.FormatInt(expires, 10),
+			c.name, c.value,
+		}, "\t"))
+	}
+	j.mu.Unlock()
+	sort.Strings(lines)
+
+	var b strings.Builder
+	b.WriteString("# Netscape HTTP Cookie File\n")
+	b.WriteString("# Generated by Wget on " + now.Format("2006-01-02 15:04:05") + ".\n")
+	b.WriteString("# Edit at your own risk.\n\n")
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+
+	if err := os.WriteFile(file, []byte(b.String()), 0600); err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("cannot save cookies: %v", err))
+	}
+	return nil
+}
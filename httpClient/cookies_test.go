@@ -0,0 +1,179 @@
+package httpClient
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"wget/exitStatus"
+)
+
+// testCookiesTxt has a domain cookie, a host-only HttpOnly cookie, a secure
+// cookie, a session cookie and an expired one
+const testCookiesTxt = "# Netscape HTTP Cookie File\n" +
+	"\n" +
+	".example.com\tTRUE\t/\tFALSE\t4102444800\tdomain\tone\n" +
+	"#HttpOnly_www.example.com\tFALSE\t/app\tFALSE\t4102444800\thttponly\ttwo\n" +
+	"www.example.com\tFALSE\t/\tTRUE\t4102444800\tsecure\tthree\r\n" +
+	"www.example.com\tFALSE\t/\tFALSE\t0\tsession\tfour\n" +
+	"www.example.com\tFALSE\t/\tFALSE\t946684800\texpired\tfive\n"
+
+// cookieLines returns the cookie lines of a cookies.txt file
+func cookieLines(t *testing.T, file string) []string {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" && (!strings.HasPrefix(line, "#") || strings.HasPrefix(line, httpOnlyPrefix)) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// cookieHeader returns the cookies the jar sends to rawURL, sorted by name
+// since cookies loaded together have no order among the same path
+func cookieHeader(t *testing.T, j *cookieJar, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pairs []string
+	for _, c := range j.Cookies(u) {
+		pairs = append(pairs, c.Name+"="+c.Value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "; ")
+}
+
+func TestCookiesRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(in, []byte(testCookiesTxt), 0600); err != nil {
+		t.Fatal(err)
+	}
+	j := newCookieJar()
+	if err := j.load(in); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://www.example.com/", "domain=one; session=four"},
+		{"https://www.example.com/app/x", "domain=one; httponly=two; secure=three; session=four"},
+		{"http://www.example.com/application", "domain=one; session=four"},
+		{"http://sub.example.com/app/", "domain=one"},
+		{"http://example.com/", "domain=one"},
+		{"http://example.org/", ""},
+	}
+	for _, tt := range tests {
+		if got := cookieHeader(t, j, tt.url); got != tt.want {
+			t.Errorf("cookies for %s = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+
+	// Saving and loading again gives the same file
+	out := filepath.Join(dir, "out.txt")
+	if err := j.save(out, true); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"#HttpOnly_www.example.com\tFALSE\t/app\tFALSE\t4102444800\thttponly\ttwo",
+		".example.com\tTRUE\t/\tFALSE\t4102444800\tdomain\tone",
+		"www.example.com\tFALSE\t/\tFALSE\t0\tsession\tfour",
+		"www.example.com\tFALSE\t/\tTRUE\t4102444800\tsecure\tthree",
+	}
+	got := cookieLines(t, out)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("saved cookies\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	again := newCookieJar()
+	if err := again.load(out); err != nil {
+		t.Fatal(err)
+	}
+	out2 := filepath.Join(dir, "out2.txt")
+	if err := again.save(out2, true); err != nil {
+		t.Fatal(err)
+	}
+	if got2 := cookieLines(t, out2); strings.Join(got2, "\n") != strings.Join(got, "\n") {
+		t.Errorf("second round trip\n%s\nwant\n%s", strings.Join(got2, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestCookiesSessionCookies(t *testing.T) {
+	j := newCookieJar()
+	u, _ := url.Parse("http://www.example.com/dir/page")
+	j.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "s"},
+		{Name: "persistent", Value: "p", MaxAge: 3600},
+		{Name: "dated", Value: "d", Expires: time.Now().Add(time.Hour)},
+	})
+
+	dir := t.TempDir()
+	tests := []struct {
+		keepSession bool
+		want        []string
+	}{
+		{false, []string{"dated", "persistent"}},
+		{true, []string{"dated", "persistent", "session"}},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(dir, "cookies.txt")
+		if err := j.save(file, tt.keepSession); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, line := range cookieLines(t, file) {
+			fields := strings.Split(line, "\t")
+			if fields[0] != "www.example.com" || fields[1] != "FALSE" || fields[2] != "/dir" {
+				t.Errorf("keepSession=%v: unexpected line %q", tt.keepSession, line)
+			}
+			if (fields[5] == "session") != (fields[4] == "0") {
+				t.Errorf("keepSession=%v: expiry %s for %s", tt.keepSession, fields[4], fields[5])
+			}
+			names = append(names, fields[5])
+		}
+		sort.Strings(names)
+		if strings.Join(names, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("keepSession=%v: saved %q, want %q", tt.keepSession, names, tt.want)
+		}
+	}
+
+	// A server deletes a cookie by expiring it
+	j.SetCookies(u, []*http.Cookie{{Name: "persistent", MaxAge: -1}})
+	if got := cookieHeader(t, j, "http://www.example.com/dir/"); got != "dated=d; session=s" {
+		t.Errorf("cookies after deletion = %q", got)
+	}
+}
+
+func TestCookiesLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content string
+		code    int
+	}{
+		{"www.example.com\tFALSE\t/\tFALSE\t0\tname\n", exitStatus.Parse},
+		{"www.example.com\tFALSE\t/\tFALSE\tnever\tname\tvalue\n", exitStatus.Parse},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(dir, "cookies.txt")
+		os.WriteFile(file, []byte(tt.content), 0600)
+		if err := newCookieJar().load(file); exitStatus.Of(err) != tt.code {
+			t.Errorf("load(%q) = %v, want exit status %d", tt.content, err, tt.code)
+		}
+	}
+	if err := newCookieJar().load(filepath.Join(dir, "missing.txt")); exitStatus.Of(err) != exitStatus.FileIO {
+		t.Errorf("missing file: %v", err)
+	}
+}
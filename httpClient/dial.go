@@ -0,0 +1,320 @@
+package httpClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+	"wget/exitStatus"
+)
+
+// dialer opens the connections of the shared transport. It resolves host
+// names and connects under their own timeouts, and gives every connection
+// an idle read timeout. It also applies the address family, --resolve
+// overrides, the local address and custom DNS servers.
+type dialer struct {
+	dnsTimeout     time.Duration
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+
+	// family is "4" or "6" to use only that address family (-4, -6)
+	family string
+	// prefer is "IPv4" or "IPv6" to try that family first (--prefer-family)
+	prefer string
+	// overrides maps "host:port" to the addresses to use (--resolve)
+	overrides map[string][]net.IP
+	// localAddr is the source address of connections (--bind-address)
+	localAddr net.IP
+	resolver  *net.Resolver
+	// unixSocket replaces every TCP connection (--unix-socket)
+	unixSocket string
+}
+
+// newDialer builds the dialer for opts
+func newDialer(opts Options) (*dialer, error) {
+	d := &dialer{
+		dnsTimeout:     opts.DNSTimeout,
+		connectTimeout: opts.ConnectTimeout,
+		readTimeout:    opts.ReadTimeout,
+		family:         opts.Family,
+		overrides:      make(map[string][]net.IP),
+		resolver:       net.DefaultResolver,
+		unixSocket:     opts.UnixSocket,
+	}
+
+	switch strings.ToLower(opts.PreferFamily) {
+	case "", "none":
+	case "ipv4":
+		d.prefer = "IPv4"
+	case "ipv6":
+		d.prefer = "IPv6"
+	default:
+		return nil, fmt.Errorf("invalid --prefer-family %q (expected IPv4, IPv6 or none)", opts.PreferFamily)
+	}
+
+	for _, entry := range opts.Resolve {
+		key, ips, err := parseResolve(entry)
+		if err != nil {
+			return nil, err
+		}
+		d.overrides[key] = ips
+	}
+
+	if opts.BindAddress != "" {
+		ips, err := net.LookupIP(opts.BindAddress)
+		if err != nil || len(ips) == 0 {
+			return nil, fmt.Errorf("invalid --bind-address %q", opts.BindAddress)
+		}
+		d.localAddr = ips[0]
+	}
+
+	if opts.DNSServers != "" {
+		servers, err := parseDNSServers(opts.DNSServers)
+		if err != nil {
+			return nil, err
+		}
+		d.resolver = newResolver(servers)
+	}
+	return d, nil
+}
+
+// parseResolve reads a curl-style "host:port:addr[,addr...]" override.
+// IPv6 addresses may be written with or without brackets.
+func parseResolve(entry string) (string, []net.IP, error) {
+	host, rest, ok1 := strings.Cut(entry, ":")
+	port, addrs, ok2 := strings.Cut(rest, ":")
+	if !ok1 || !ok2 || host == "" || port == "" {
+		return "", nil, fmt.Errorf("invalid --resolve %q (expected host:port:address)", entry)
+	}
+	var ips []net.IP
+	for _, addr := range strings.Split(addrs, ",") {
+		ip := net.ParseIP(strings.Trim(strings.TrimSpace(addr), "[]"))
+		if ip == nil {
+			return "", nil, fmt.Errorf("invalid address %q in --resolve %q", addr, entry)
+		}
+		ips = append(ips, ip)
+	}
+	return net.JoinHostPort(strings.ToLower(host), port), ips, nil
+}
+
+// parseDNSServers reads a comma-separated list of DNS servers, each an IP
+// address with an optional port
+func parseDNSServers(list string) ([]string, error) {
+	var servers []string
+	for _, server := range strings.Split(list, ",") {
+		server = strings.TrimSpace(server)
+		if ip := net.ParseIP(strings.Trim(server, "[]")); ip != nil {
+			server = net.JoinHostPort(ip.String(), "53")
+		}
+		host, _, err := net.SplitHostPort(server)
+		if err != nil || net.ParseIP(host) == nil {
+			return nil, fmt.Errorf("invalid DNS server %q", server)
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+// newResolver returns a resolver that asks servers in turn instead of the
+// ones in /etc/resolv.conf
+func newResolver(servers []string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var netDialer net.Dialer
+			var err error
+			for _, server := range servers {
+				var conn net.Conn
+				if conn, err = netDialer.DialContext(ctx, network, server); err == nil {
+					return conn, nil
+				}
+			}
+			return nil, err
+		},
+	}
+}
+
+// DialContext is the http.Transport DialContext function
+func (d *dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.unixSocket != "" {
+		// The URL still supplies the Host header and path
+		conn, err := d.connect(ctx, "unix", d.unixSocket)
+		if err != nil {
+			return nil, err
+		}
+		return d.wrap(conn), nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := d.addresses(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try each address in turn until one accepts
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := d.connect(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return d.wrap(conn), nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, firstErr
+}
+
+// addresses returns the addresses to try for host:port, in order
+func (d *dialer) addresses(ctx context.Context, host, port string) ([]net.IP, error) {
+	ips, ok := d.overrides[net.JoinHostPort(strings.ToLower(host), port)]
+	if !ok {
+		var err error
+		if ips, err = d.lookup(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+
+	// A local address only reaches addresses of its own family
+	var v4, v6, usable []net.IP
+	for _, ip := range ips {
+		isV4 := ip.To4() != nil
+		if d.localAddr != nil && isV4 != (d.localAddr.To4() != nil) {
+			continue
+		}
+		usable = append(usable, ip)
+		if isV4 {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+
+	switch {
+	case d.family == "4":
+		ips = v4
+	case d.family == "6":
+		ips = v6
+	case d.prefer == "IPv4":
+		ips = append(v4, v6...)
+	case d.prefer == "IPv6":
+		ips = append(v6, v4...)
+	default:
+		ips = usable
+	}
+	if len(ips) == 0 {
+		family := "usable"
+		if d.family != "" {
+			family = "IPv" + d.family
+		}
+		// Reported like a failed lookup, which isn't worth retrying
+		return nil, &net.DNSError{Err: "no " + family + " address", Name: host, IsNotFound: true}
+	}
+	return ips, nil
+}
+
+// resolveHost returns the addresses DialContext would try for host when
+// there is no port to go with it, as for the PAC dnsResolve function. A
+// --resolve override for any port of host counts.
+func (d *dialer) resolveHost(ctx context.Context, host string) ([]net.IP, error) {
+	keys := make([]string, 0, len(d.overrides))
+	for key := range d.overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if h, port, _ := net.SplitHostPort(key); h == strings.ToLower(host) {
+			return d.addresses(ctx, host, port)
+		}
+	}
+	return d.addresses(ctx, host, "")
+}
+
+// lookup resolves host within the DNS timeout
+func (d *dialer) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	if d.dnsTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.dnsTimeout)
+		defer cancel()
+	}
+
+	addrs, err := d.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		if d.dnsTimeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, exitStatus.Wrap(exitStatus.Timeout, fmt.Errorf("DNS lookup of %s timed out after %v", host, d.dnsTimeout))
+		}
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
+
+// connect opens a connection to address within the connect timeout
+func (d *dialer) connect(ctx context.Context, network, address string) (net.Conn, error) {
+	netDialer := &net.Dialer{Timeout: d.connectTimeout, KeepAlive: 30 * time.Second}
+	if d.localAddr != nil && network != "unix" {
+		netDialer.LocalAddr = &net.TCPAddr{IP: d.localAddr}
+	}
+	conn, err := netDialer.DialContext(ctx, network, address)
+	var netErr net.Error
+	if err != nil && d.connectTimeout > 0 && errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+		return nil, exitStatus.Wrap(exitStatus.Timeout, fmt.Errorf("connecting to %s timed out after %v", address, d.connectTimeout))
+	}
+	return conn, err
+}
+
+// wrap adds the read timeout to conn
+func (d *dialer) wrap(conn net.Conn) net.Conn {
+	if d.readTimeout <= 0 {
+		return conn
+	}
+	return &idleConn{Conn: conn, timeout: d.readTimeout}
+}
+
+// idleConn fails a read that waits longer than timeout for data, so a
+// stalled server can't hang the download
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+// Read reads from the connection, pushing the deadline back each time
+func (c *idleConn) Read(p []byte) (int, error) {
+	c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	n, err := c.Conn.Read(p)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		err = &readTimeoutError{timeout: c.timeout, err: err}
+	}
+	return n, err
+}
+
+// readTimeoutError explains a read timeout. It stays a net.Error so
+// net/http and the exit status see it as a timeout.
+type readTimeoutError struct {
+	timeout time.Duration
+	err     error
+}
+
+func (e *readTimeoutError) Error() string {
+	return fmt.Sprintf("no data received for %v (read timeout)", e.timeout)
+}
+func (e *readTimeoutError) Unwrap() error   { return e.err }
+func (e *readTimeoutError) Timeout() bool   { return true }
+func (e *readTimeoutError) Temporary() bool { return true }
@@ -0,0 +1,117 @@
+package httpClient
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestClient(t *testing.T, opts Options) *Client {
+	t.Helper()
+	opts.NetrcFile = filepath.Join(t.TempDir(), "netrc")
+	opts.NoHSTS, opts.NoCookies, opts.NoProxy = true, true, true
+	c, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func getBody(t *testing.T, c *Client, url string) string {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// echoHost answers with the Host header and path of the request
+var echoHost = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, r.Host+r.URL.Path)
+})
+
+func TestUnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, which t.TempDir can exceed
+	dir, err := os.MkdirTemp("", "wget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "api.sock")
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skip("unix sockets unavailable:", err)
+	}
+	server := httptest.NewUnstartedServer(echoHost)
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	c := newTestClient(t, Options{UnixSocket: socket})
+	if got, want := getBody(t, c, "http://api.local/v1/status"), "api.local/v1/status"; got != want {
+		t.Errorf("body %q, want %q", got, want)
+	}
+}
+
+func TestResolveOverride(t *testing.T) {
+	server := httptest.NewServer(echoHost)
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	// The name doesn't exist, so only the override can reach the server
+	c := newTestClient(t, Options{Resolve: []string{"Pinned.Invalid:" + port + ":127.0.0.1"}})
+	url := "http://pinned.invalid:" + port + "/file"
+	if got, want := getBody(t, c, url), "pinned.invalid:"+port+"/file"; got != want {
+		t.Errorf("body %q, want %q", got, want)
+	}
+
+	// The override applies to its port only
+	if _, err := c.Get("http://pinned.invalid:1/file"); err == nil {
+		t.Error("expected the override not to apply to another port")
+	}
+}
+
+func TestParseResolve(t *testing.T) {
+	tests := []struct {
+		entry string
+		key   string
+		ips   []string
+	}{
+		{"example.com:443:127.0.0.1", "example.com:443", []string{"127.0.0.1"}},
+		{"Example.com:80:10.0.0.1,10.0.0.2", "example.com:80", []string{"10.0.0.1", "10.0.0.2"}},
+		{"example.com:443:[::1]", "example.com:443", []string{"::1"}},
+		{"example.com:443:::1", "example.com:443", []string{"::1"}},
+		{"example.com:443", "", nil},
+		{":443:127.0.0.1", "", nil},
+		{"example.com:443:not-an-ip", "", nil},
+	}
+	for _, tt := range tests {
+		key, ips, err := parseResolve(tt.entry)
+		if tt.key == "" {
+			if err == nil {
+				t.Errorf("parseResolve(%q) succeeded, want an error", tt.entry)
+			}
+			continue
+		}
+		if err != nil || key != tt.key || len(ips) != len(tt.ips) {
+			t.Errorf("parseResolve(%q) = %q, %v, %v; want %q, %v", tt.entry, key, ips, err, tt.key, tt.ips)
+			continue
+		}
+		for i, ip := range ips {
+			if ip.String() != tt.ips[i] {
+				t.Errorf("parseResolve(%q) address %d = %s, want %s", tt.entry, i, ip, tt.ips[i])
+			}
+		}
+	}
+}
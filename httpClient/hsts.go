@@ -0,0 +1,210 @@
+package httpClient
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wget/exitStatus"
+)
+
+// hstsHeader is the first line of a GNU wget HSTS database
+const hstsHeader = "# HSTS 1.0 Known Hosts database for GNU Wget.\n" +
+	"# Edit at your own risk.\n" +
+	"# <hostname>\t<port>\t<incl. subdomains>\t<created>\t<max-age>\n"
+
+// hstsEntry is a host that asked to be reached over https only
+type hstsEntry struct {
+	includeSubdomains bool
+	created           int64
+	maxAge            int64
+}
+
+// expired reports whether the policy has run out
+func (e hstsEntry) expired(now time.Time) bool {
+	return now.Unix() >= e.created+e.maxAge
+}
+
+// hstsStore is the HSTS database (~/.wget-hsts). It is read at the start of
+// a run, updated from Strict-Transport-Security headers and written by Save.
+type hstsStore struct {
+	mu      sync.Mutex
+	file    string
+	hosts   map[string]hstsEntry
+	changed bool
+	// readOnly applies the known policies without recording new ones,
+	// for --no-check-certificate
+	readOnly bool
+}
+
+// loadHSTS reads the database in file; a missing file is an empty database
+func loadHSTS(file string) (*hstsStore, error) {
+	s := &hstsStore{file: file, hosts: make(map[string]hstsEntry)}
+	if file == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			s.file = filepath.Join(home, ".wget-hsts")
+		}
+	}
+	if s.file == "" {
+		return s, nil
+	}
+
+	f, err := os.Open(s.file)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("cannot read HSTS database: %v", err))
+	}
+	defer f.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// host, port, include subdomains, created, max-age
+		fields := strings.Fields(line)
+		if len(fields) != 5 {
+			continue
+		}
+		created, err1 := strconv.ParseInt(fields[3], 10, 64)
+		maxAge, err2 := strconv.ParseInt(fields[4], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		entry := hstsEntry{includeSubdomains: fields[2] == "1", created: created, maxAge: maxAge}
+		if !entry.expired(now) {
+			s.hosts[hstsKey(fields[0], fields[1])] = entry
+		}
+	}
+	return s, scanner.Err()
+}
+
+// hstsKey identifies a host in the database. Port 0 stands for the
+// default port, which is the only one the upgrade applies to.
+func hstsKey(host, port string) string {
+	host = strings.ToLower(host)
+	if port == "" || port == "0" || port == "443" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// upgrade rewrites an http:// URL to https:// when its host has an HSTS
+// policy, directly or through a parent domain with includeSubDomains
+func (s *hstsStore) upgrade(u *url.URL) {
+	if u.Scheme != "http" || net.ParseIP(u.Hostname()) != nil {
+		return
+	}
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "80" {
+		port = ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for domain, subdomain := host, false; domain != ""; subdomain = true {
+		entry, ok := s.hosts[hstsKey(domain, port)]
+		if ok && !entry.expired(now) && (!subdomain || entry.includeSubdomains) {
+			u.Scheme = "https"
+			u.Host = host
+			if port != "" {
+				u.Host = net.JoinHostPort(host, port)
+			}
+			return
+		}
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+}
+
+// record stores the Strict-Transport-Security policy of resp. Like browsers
+// it only trusts the header over https and for host names, and not when the
+// certificate went unchecked (RFC 6797 section 8.1).
+func (s *hstsStore) record(resp *http.Response) {
+	header := resp.Header.Get("Strict-Transport-Security")
+	u := resp.Request.URL
+	if s.readOnly || header == "" || u.Scheme != "https" || net.ParseIP(u.Hostname()) != nil || resp.TLS == nil {
+		return
+	}
+
+	entry := hstsEntry{maxAge: -1, created: time.Now().Unix()}
+	for _, directive := range strings.Split(header, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			if n, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(value), `"`), 10, 64); err == nil && n >= 0 {
+				entry.maxAge = n
+			}
+		case "includesubdomains":
+			entry.includeSubdomains = true
+		}
+	}
+	if entry.maxAge < 0 {
+		// max-age is required
+		return
+	}
+
+	key := hstsKey(u.Hostname(), u.Port())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry.maxAge == 0 {
+		// max-age=0 removes the policy
+		if _, ok := s.hosts[key]; ok {
+			delete(s.hosts, key)
+			s.changed = true
+		}
+		return
+	}
+	s.hosts[key] = entry
+	s.changed = true
+}
+
+// save writes the database back if the run changed it
+func (s *hstsStore) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.changed || s.file == "" {
+		return nil
+	}
+
+	var lines []string
+	now := time.Now()
+	for key, entry := range s.hosts {
+		if entry.expired(now) {
+			continue
+		}
+		host, port := key, "0"
+		if h, p, err := net.SplitHostPort(key); err == nil {
+			host, port = h, p
+		}
+		subdomains := "0"
+		if entry.includeSubdomains {
+			subdomains = "1"
+		}
+		lines = append(lines, strings.Join([]string{host, port, subdomains,
+			strconv.FormatInt(entry.created, 10), strconv.FormatInt(entry.maxAge, 10)}, "\t"))
+	}
+	sort.Strings(lines)
+
+	data := hstsHeader
+	for _, line := range lines {
+		data += line + "\n"
+	}
+	if err := os.WriteFile(s.file, []byte(data), 0644); err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("cannot save HSTS database: %v", err))
+	}
+	s.changed = false
+	return nil
+}
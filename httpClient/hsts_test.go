@@ -0,0 +1,195 @@
+package httpClient
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHSTSUpgrade(t *testing.T) {
+	now := time.Now().Unix()
+	s := &hstsStore{hosts: map[string]hstsEntry{
+		"example.com":      {includeSubdomains: true, created: now, maxAge: 3600},
+		"exact.test":       {created: now, maxAge: 3600},
+		"alt.test:8443":    {created: now, maxAge: 3600},
+		"expired.test":     {created: now - 7200, maxAge: 3600},
+		"sub.expired.test": {created: now, maxAge: 3600},
+	}}
+	tests := []struct {
+		url, want string
+	}{
+		{"http://example.com/a", "https://example.com/a"},
+		{"http://EXAMPLE.com:80/a", "https://example.com/a"},
+		{"http://www.example.com/a?q", "https://www.example.com/a?q"},
+		{"http://exact.test/", "https://exact.test/"},
+		{"http://www.exact.test/", "http://www.exact.test/"},
+		{"http://alt.test:8443/", "https://alt.test:8443/"},
+		{"http://alt.test/", "http://alt.test/"},
+		{"http://exact.test:8080/", "http://exact.test:8080/"},
+		{"http://expired.test/", "http://expired.test/"},
+		{"http://sub.expired.test/", "https://sub.expired.test/"},
+		{"http://127.0.0.1/", "http://127.0.0.1/"},
+		{"ftp://example.com/", "ftp://example.com/"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		s.upgrade(u)
+		if u.String() != tt.want {
+			t.Errorf("upgrade(%s) = %s, want %s", tt.url, u, tt.want)
+		}
+	}
+}
+
+func TestHSTSRecord(t *testing.T) {
+	response := func(rawURL, header string, secure bool) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, rawURL, nil)
+		resp := &http.Response{Request: req, Header: http.Header{}}
+		if header != "" {
+			resp.Header.Set("Strict-Transport-Security", header)
+		}
+		if secure {
+			resp.TLS = req.TLS
+		}
+		return resp
+	}
+
+	tests := []struct {
+		name     string
+		resp     *http.Response
+		readOnly bool
+		want     map[string]hstsEntry
+	}{
+		{"policy", response("https://a.test/", "max-age=600", true), false,
+			map[string]hstsEntry{"old.test": {}, "a.test": {maxAge: 600}}},
+		{"subdomains and quoted max-age", response("https://a.test:8443/", `max-age="600"; includeSubDomains`, true), false,
+			map[string]hstsEntry{"old.test": {}, "a.test:8443": {maxAge: 600, includeSubdomains: true}}},
+		{"max-age=0 removes", response("https://old.test/", "max-age=0", true), false,
+			map[string]hstsEntry{}},
+		{"no max-age", response("https://a.test/", "includeSubDomains", true), false,
+			map[string]hstsEntry{"old.test": {}}},
+		{"over http", response("http://a.test/", "max-age=600", false), false,
+			map[string]hstsEntry{"old.test": {}}},
+		{"IP address", response("https://127.0.0.1/", "max-age=600", true), false,
+			map[string]hstsEntry{"old.test": {}}},
+		{"unchecked certificate", response("https://a.test/", "max-age=600", true), true,
+			map[string]hstsEntry{"old.test": {}}},
+	}
+	for _, tt := range tests {
+		s := &hstsStore{hosts: map[string]hstsEntry{"old.test": {}}, readOnly: tt.readOnly}
+		s.record(tt.resp)
+		if len(s.hosts) != len(tt.want) {
+			t.Errorf("%s: hosts %v, want %v", tt.name, s.hosts, tt.want)
+			continue
+		}
+		for key, want := range tt.want {
+			got, ok := s.hosts[key]
+			got.created = 0
+			if !ok || got != want {
+				t.Errorf("%s: %s = %+v, want %+v", tt.name, key, got, want)
+			}
+		}
+	}
+}
+
+// newHSTSServer starts an https server for example.com that sends an HSTS
+// policy from /policy and redirects /redirect to the http URL of /target.
+// It returns the https URL of the server, reached through a --resolve
+// override, and the CA file that makes it trusted.
+func newHSTSServer(t *testing.T) (string, Options) {
+	t.Helper()
+	var base string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/policy":
+			w.Header().Set("Strict-Transport-Security", "max-age=3600")
+		case "/redirect":
+			http.Redirect(w, r, "http"+strings.TrimPrefix(base, "https")+"/target", http.StatusFound)
+			return
+		}
+		w.Write([]byte("secure " + r.URL.Path))
+	}))
+	// Plain http requests to it are answered with 400 Bad Request
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	base = "https://example.com:" + port
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(ca, pem.EncodeToMemory(block), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return base, Options{
+		CACertificate: ca,
+		Resolve:       []string{"example.com:" + port + ":127.0.0.1"},
+		NetrcFile:     filepath.Join(t.TempDir(), "netrc"),
+		HSTSFile:      filepath.Join(t.TempDir(), "hsts"),
+		NoCookies:     true,
+		NoProxy:       true,
+	}
+}
+
+func TestHSTSClient(t *testing.T) {
+	base, opts := newHSTSServer(t)
+	plain := "http" + strings.TrimPrefix(base, "https")
+
+	c, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Before the policy is known the redirect goes to http
+	if status := getStatus(t, c, base+"/redirect"); status != http.StatusBadRequest {
+		t.Fatalf("redirect before the policy: status %d, want 400 from plain http", status)
+	}
+	getBody(t, c, base+"/policy")
+
+	// The policy upgrades http URLs, including redirect targets
+	if got := getBody(t, c, plain+"/page"); got != "secure /page" {
+		t.Errorf("upgraded request got %q", got)
+	}
+	if got := getBody(t, c, base+"/redirect"); got != "secure /target" {
+		t.Errorf("redirect got %q", got)
+	}
+
+	// The policy is saved and applies to the next run
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	next, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := getBody(t, next, plain+"/again"); got != "secure /again" {
+		t.Errorf("request in the next run got %q", got)
+	}
+}
+
+func TestHSTSNotRecordedWithoutCertificateCheck(t *testing.T) {
+	base, opts := newHSTSServer(t)
+	opts.CACertificate, opts.NoCheckCertificate = "", true
+
+	c, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	getBody(t, c, base+"/policy")
+	if status := getStatus(t, c, "http"+strings.TrimPrefix(base, "https")+"/page"); status != http.StatusBadRequest {
+		t.Errorf("status %d: http request was upgraded by a policy received without a certificate check", status)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(opts.HSTSFile); !os.IsNotExist(err) {
+		t.Errorf("HSTS database written: %v", err)
+	}
+}
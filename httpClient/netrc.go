@@ -0,0 +1,111 @@
+package httpClient
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// netrcEntry is one machine (or the default) from a .netrc file
+type netrcEntry struct {
+	login    string
+	password string
+}
+
+// netrc holds the credentials read from ~/.netrc, keyed by host name
+type netrc struct {
+	machines map[string]netrcEntry
+	fallback *netrcEntry
+}
+
+// loadNetrc reads path, or $NETRC or ~/.netrc when path is empty. A
+// missing file gives an empty set of credentials.
+func loadNetrc(path string) *netrc {
+	n := &netrc{machines: make(map[string]netrcEntry)}
+	if path == "" {
+		path = os.Getenv("NETRC")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return n
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return n
+	}
+	n.parse(string(data))
+	return n
+}
+
+// parse reads the whitespace-separated "machine NAME login USER password
+// PASS" tokens of a .netrc file. Macro definitions are skipped.
+func (n *netrc) parse(data string) {
+	var current *netrcEntry
+	var host string
+	save := func() {
+		if current == nil {
+			return
+		}
+		if host == "" {
+			n.fallback = current
+		} else if _, ok := n.machines[host]; !ok {
+			// The first entry for a host wins
+			n.machines[host] = *current
+		}
+	}
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			next := func() string {
+				if j+1 < len(fields) {
+					j++
+					return fields[j]
+				}
+				return ""
+			}
+
+			switch fields[j] {
+			case "machine":
+				save()
+				current, host = &netrcEntry{}, strings.ToLower(next())
+			case "default":
+				save()
+				current, host = &netrcEntry{}, ""
+			case "login":
+				if current != nil {
+					current.login = next()
+				}
+			case "password":
+				if current != nil {
+					current.password = next()
+				}
+			case "account":
+				next()
+			case "macdef":
+				// A macro runs until the next blank line
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+	save()
+}
+
+// lookup returns the credentials for host, falling back to the default entry
+func (n *netrc) lookup(host string) (netrcEntry, bool) {
+	if entry, ok := n.machines[strings.ToLower(host)]; ok {
+		return entry, true
+	}
+	if n.fallback != nil {
+		return *n.fallback, true
+	}
+	return netrcEntry{}, false
+}
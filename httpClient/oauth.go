@@ -0,0 +1,114 @@
+package httpClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"wget/exitStatus"
+)
+
+// expiryMargin refreshes tokens a little before they run out, so a request
+// doesn't leave with a token that expires on the way
+const expiryMargin = 30 * time.Second
+
+// tokenSource hands out bearer tokens: either a fixed --bearer-token, or
+// one obtained from an OAuth2 token endpoint with the client credentials
+// grant and refreshed when it expires or is rejected.
+type tokenSource struct {
+	mu           sync.Mutex
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	http         *http.Client
+
+	token  string
+	expiry time.Time
+}
+
+// tokenResponse is the JSON body returned by the token endpoint
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// canRefresh reports whether a rejected token can be replaced
+func (t *tokenSource) canRefresh() bool {
+	return t.tokenURL != ""
+}
+
+// get returns a valid token, fetching a new one if there is none yet or it
+// is about to expire
+func (t *tokenSource) get() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && (t.expiry.IsZero() || time.Until(t.expiry) > expiryMargin) {
+		return t.token, nil
+	}
+	if !t.canRefresh() {
+		return t.token, nil
+	}
+	return t.fetch()
+}
+
+// refresh replaces rejected with a new token. Requests that were rejected
+// at the same time share a single refresh.
+func (t *tokenSource) refresh(rejected string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != rejected {
+		return t.token, nil
+	}
+	return t.fetch()
+}
+
+// fetch asks the token endpoint for a new token; t.mu must be held
+func (t *tokenSource) fetch() (string, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequest(http.MethodPost, t.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(t.clientID), url.QueryEscape(t.clientSecret))
+
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return "", exitStatus.Wrap(exitStatus.Protocol, fmt.Errorf("invalid token response: %v", err))
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		reason := resp.Status
+		if body.Error != "" {
+			reason = body.Error
+			if body.Description != "" {
+				reason += ": " + body.Description
+			}
+		}
+		return "", exitStatus.Wrap(exitStatus.Auth, fmt.Errorf("token endpoint refused the client credentials: %s", reason))
+	}
+	if body.TokenType != "" && !strings.EqualFold(body.TokenType, "bearer") {
+		return "", exitStatus.Wrap(exitStatus.Auth, fmt.Errorf("unsupported token type %q", body.TokenType))
+	}
+
+	t.token = body.AccessToken
+	t.expiry = time.Time{}
+	if body.ExpiresIn > 0 {
+		t.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return t.token, nil
+}
@@ -0,0 +1,165 @@
+package httpClient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"wget/exitStatus"
+)
+
+// tokenServer is an OAuth2 token endpoint together with a resource that
+// accepts only the token handed out last
+type tokenServer struct {
+	mu      sync.Mutex
+	issued  int
+	current string
+	refuse  bool
+	// rejectAll makes the resource refuse even fresh tokens
+	rejectAll bool
+
+	resourceCalls int
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/token":
+		id, secret, _ := r.BasicAuth()
+		if r.FormValue("grant_type") != "client_credentials" || id != "client" || secret != "s3cret" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+		if s.refuse {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"client disabled"}`)
+			return
+		}
+		s.issued++
+		s.current = fmt.Sprintf("token-%d", s.issued)
+		fmt.Fprintf(w, `{"access_token":%q,"token_type":"Bearer","expires_in":3600}`, s.current)
+	case "/resource":
+		s.resourceCalls++
+		if s.rejectAll || r.Header.Get("Authorization") != "Bearer "+s.current {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}
+}
+
+// revoke makes the resource reject every token issued so far
+func (s *tokenServer) revoke() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = "revoked"
+}
+
+func newOAuthClient(t *testing.T, server *httptest.Server) *Client {
+	return newTestClient(t, Options{
+		OAuthTokenURL: server.URL + "/token",
+		ClientID:      "client",
+		ClientSecret:  "s3cret",
+	})
+}
+
+func getStatus(t *testing.T, c *Client, url string) int {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestOAuthFetchesToken(t *testing.T) {
+	tokens := &tokenServer{}
+	server := httptest.NewServer(tokens)
+	defer server.Close()
+	c := newOAuthClient(t, server)
+
+	for i := 0; i < 2; i++ {
+		if status := getStatus(t, c, server.URL+"/resource"); status != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, status)
+		}
+	}
+	if tokens.issued != 1 {
+		t.Errorf("token fetched %d times, want once", tokens.issued)
+	}
+}
+
+func TestOAuthRefreshesExpiredToken(t *testing.T) {
+	tokens := &tokenServer{}
+	server := httptest.NewServer(tokens)
+	defer server.Close()
+	c := newOAuthClient(t, server)
+
+	getStatus(t, c, server.URL+"/resource")
+	c.tokens.expiry = time.Now().Add(expiryMargin / 2)
+
+	if status := getStatus(t, c, server.URL+"/resource"); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if tokens.issued != 2 {
+		t.Errorf("token fetched %d times, want 2", tokens.issued)
+	}
+	if tokens.resourceCalls != 2 {
+		t.Errorf("resource requested %d times, want 2 (no rejected request)", tokens.resourceCalls)
+	}
+}
+
+func TestOAuthRetriesOnceOn401(t *testing.T) {
+	tokens := &tokenServer{}
+	server := httptest.NewServer(tokens)
+	defer server.Close()
+	c := newOAuthClient(t, server)
+
+	getStatus(t, c, server.URL+"/resource")
+	tokens.revoke()
+
+	if status := getStatus(t, c, server.URL+"/resource"); status != http.StatusOK {
+		t.Fatalf("status %d after refresh", status)
+	}
+	if tokens.issued != 2 || tokens.resourceCalls != 3 {
+		t.Errorf("issued %d tokens for %d requests, want 2 for 3", tokens.issued, tokens.resourceCalls)
+	}
+}
+
+func TestOAuthGivesUpAfterOneRefresh(t *testing.T) {
+	tokens := &tokenServer{rejectAll: true}
+	server := httptest.NewServer(tokens)
+	defer server.Close()
+	c := newOAuthClient(t, server)
+
+	if status := getStatus(t, c, server.URL+"/resource"); status != http.StatusUnauthorized {
+		t.Fatalf("status %d, want 401", status)
+	}
+	if tokens.issued != 2 || tokens.resourceCalls != 2 {
+		t.Errorf("issued %d tokens for %d requests, want 2 for 2", tokens.issued, tokens.resourceCalls)
+	}
+}
+
+func TestOAuthRefreshFailure(t *testing.T) {
+	tokens := &tokenServer{}
+	server := httptest.NewServer(tokens)
+	defer server.Close()
+	c := newOAuthClient(t, server)
+
+	getStatus(t, c, server.URL+"/resource")
+	tokens.revoke()
+	tokens.refuse = true
+
+	_, err := c.Get(server.URL + "/resource")
+	if err == nil {
+		t.Fatal("expected an error when the token endpoint refuses")
+	}
+	if code := exitStatus.Of(err); code != exitStatus.Auth {
+		t.Errorf("exit status %d, want %d (%v)", code, exitStatus.Auth, err)
+	}
+}
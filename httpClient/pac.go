@@ -0,0 +1,335 @@
+package httpClient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// proxyRecheck is how long a proxy from a PAC failover chain is remembered
+// as reachable or unreachable before it is tried again
+const proxyRecheck = time.Minute
+
+// pacTimeout bounds a dnsResolve lookup or a proxy reachability check
+const pacTimeout = 5 * time.Second
+
+// pacHelpers are the standard PAC functions that can be written in
+// JavaScript itself; dnsResolve and myIpAddress are provided from Go
+const pacHelpers = `
+function isPlainHostName(host) { return host.indexOf('.') < 0; }
+function dnsDomainIs(host, domain) {
+	return host.length >= domain.length && host.substring(host.length - domain.length) == domain;
+}
+function localHostOrDomainIs(host, hostdom) { return host == hostdom || hostdom.lastIndexOf(host + '.', 0) == 0; }
+function isResolvable(host) { return dnsResolve(host) != null; }
+function dnsDomainLevels(host) { return host.split('.').length - 1; }
+function convert_addr(ipchars) {
+	var b = ipchars.split('.');
+	return ((b[0] & 0xff) << 24) | ((b[1] & 0xff) << 16) | ((b[2] & 0xff) << 8) | (b[3] & 0xff);
+}
+function isInNet(ipaddr, pattern, maskstr) {
+	var ip = /^\d+\.\d+\.\d+\.\d+$/.test(ipaddr) ? ipaddr : dnsResolve(ipaddr);
+	if (ip == null) return false;
+	return (convert_addr(ip) & convert_addr(maskstr)) == (convert_addr(pattern) & convert_addr(maskstr));
+}
+function shExpMatch(str, shexp) {
+	var re = shexp.replace(/[.+^${}()|[\]\\]/g, '\\$&').replace(/\*/g, '.*').replace(/\?/g, '.');
+	return new RegExp('^' + re + '$').test(str);
+}
+function pacArgs(args) {
+	var list = Array.prototype.slice.call(args), gmt = list[list.length - 1] == 'GMT';
+	if (gmt) list.pop();
+	var now = new Date();
+	return {list: list, gmt: gmt, now: now};
+}
+function weekdayRange() {
+	var a = pacArgs(arguments), days = ['SUN', 'MON', 'TUE', 'WED', 'THU', 'FRI', 'SAT'];
+	var today = a.gmt ? a.now.getUTCDay() : a.now.getDay();
+	var d1 = days.indexOf(a.list[0]), d2 = a.list.length > 1 ? days.indexOf(a.list[1]) : d1;
+	if (d1 < 0 || d2 < 0) return false;
+	return d1 <= d2 ? (today >= d1 && today <= d2) : (today >= d1 || today <= d2);
+}
+function timeRange() {
+	var a = pacArgs(arguments), n = a.list.map(Number);
+	var t = a.gmt ? a.now.getUTCHours() * 3600 + a.now.getUTCMinutes() * 60 + a.now.getUTCSeconds()
+		: a.now.getHours() * 3600 + a.now.getMinutes() * 60 + a.now.getSeconds();
+	var from, to;
+	switch (n.length) {
+	case 1: from = n[0] * 3600; to = from + 3600; break;
+	case 2: from = n[0] * 3600; to = n[1] * 3600; break;
+	case 4: from = n[0] * 3600 + n[1] * 60; to = n[2] * 3600 + n[3] * 60; break;
+	case 6: from = n[0] * 3600 + n[1] * 60 + n[2]; to = n[3] * 3600 + n[4] * 60 + n[5] + 1; break;
+	default: return false;
+	}
+	return from <= to ? (t >= from && t < to) : (t >= from || t < to);
+}
+function dateRange() {
+	var a = pacArgs(arguments), months = ['JAN', 'FEB', 'MAR', 'APR', 'MAY', 'JUN', 'JUL', 'AUG', 'SEP', 'OCT', 'NOV', 'DEC'];
+	var n = a.list.length, single = n == 1 || n == 3;
+	if (n == 0 || n == 5 || n > 6) return false;
+	function parse(list) {
+		var v = {};
+		for (var i = 0; i < list.length; i++) {
+			var m = months.indexOf(String(list[i]).toUpperCase());
+			if (m >= 0) v.M = m; else if (list[i] > 31) v.Y = Number(list[i]); else v.D = Number(list[i]);
+		}
+		return v;
+	}
+	var from = single ? parse(a.list) : parse(a.list.slice(0, n / 2)), to = single ? from : parse(a.list.slice(n / 2));
+	var now = a.gmt ? {Y: a.now.getUTCFullYear(), M: a.now.getUTCMonth(), D: a.now.getUTCDate()}
+		: {Y: a.now.getFullYear(), M: a.now.getMonth(), D: a.now.getDate()};
+	function key(v) {
+		return ('Y' in from ? v.Y * 10000 : 0) + ('M' in from ? v.M * 100 : 0) + ('D' in from ? v.D : 0);
+	}
+	var f = key(from), t = key(to), c = key(now);
+	return f <= t ? (c >= f && c <= t) : (c >= f || c <= t);
+}
+function alert(message) {}
+`
+
+// pac chooses proxies by running the FindProxyForURL function of a proxy
+// auto-config file (--proxy-pac). A JavaScript runtime can only run one
+// call at a time, so concurrent requests each take one from a pool instead
+// of waiting for each other's dnsResolve lookups. Lookups and proxy checks
+// go through dialer, like every other connection.
+type pac struct {
+	location string
+	program  *goja.Program
+	runtimes sync.Pool
+	dialer   *dialer
+
+	user, password string
+
+	mu      sync.Mutex
+	checked map[string]proxyCheck
+}
+
+// pacRuntime is a JavaScript runtime with the PAC script loaded
+type pacRuntime struct {
+	vm   *goja.Runtime
+	find goja.Callable
+}
+
+// proxyCheck remembers whether a proxy accepted connections
+type proxyCheck struct {
+	up   bool
+	when time.Time
+}
+
+// pacHelpersProgram is pacHelpers compiled once for every runtime
+var pacHelpersProgram = goja.MustCompile("pac-helpers.js", pacHelpers, false)
+
+// loadPAC reads the PAC script from a file or an http(s) URL, fetched with
+// client, and checks that it defines FindProxyForURL
+func loadPAC(location, user, password string, client *http.Client, dialer *dialer) (*pac, error) {
+	script, err := readPAC(location, client)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read proxy auto-config %s: %v", location, err)
+	}
+	program, err := goja.Compile(location, script, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy auto-config %s: %v", location, err)
+	}
+
+	p := &pac{location: location, program: program, dialer: dialer, user: user, password: password, checked: make(map[string]proxyCheck)}
+	runtime, err := p.newRuntime()
+	if err != nil {
+		return nil, err
+	}
+	p.runtimes.Put(runtime)
+	return p, nil
+}
+
+// newRuntime starts a JavaScript runtime and runs the PAC script in it
+func (p *pac) newRuntime() (*pacRuntime, error) {
+	vm := goja.New()
+	vm.Set("dnsResolve", func(host string) goja.Value {
+		if ip := p.resolveIPv4(host); ip != "" {
+			return vm.ToValue(ip)
+		}
+		return goja.Null()
+	})
+	vm.Set("myIpAddress", p.myIPAddress)
+
+	if _, err := vm.RunProgram(pacHelpersProgram); err != nil {
+		return nil, err
+	}
+	if _, err := vm.RunProgram(p.program); err != nil {
+		return nil, fmt.Errorf("invalid proxy auto-config %s: %v", p.location, err)
+	}
+	find, ok := goja.AssertFunction(vm.Get("FindProxyForURL"))
+	if !ok {
+		return nil, fmt.Errorf("proxy auto-config %s has no FindProxyForURL function", p.location)
+	}
+	return &pacRuntime{vm: vm, find: find}, nil
+}
+
+// findProxy runs FindProxyForURL in a runtime of its own
+func (p *pac) findProxy(target, host string) (string, error) {
+	runtime, ok := p.runtimes.Get().(*pacRuntime)
+	if !ok {
+		var err error
+		if runtime, err = p.newRuntime(); err != nil {
+			return "", err
+		}
+	}
+	defer p.runtimes.Put(runtime)
+
+	result, err := runtime.find(goja.Undefined(), runtime.vm.ToValue(target), runtime.vm.ToValue(host))
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// readPAC fetches a PAC script. URLs are fetched with client, which never
+// goes through a proxy.
+func readPAC(location string, client *http.Client) (string, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		data, err := os.ReadFile(location)
+		return string(data), err
+	}
+
+	resp, err := client.Get(location)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("server responded with %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+// resolveIPv4 returns the first IPv4 address of host, or "" if it has none
+func (p *pac) resolveIPv4(host string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), pacTimeout)
+	defer cancel()
+	ips, err := p.dialer.resolveHost(ctx, host)
+	if err != nil {
+		return ""
+	}
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4.String()
+		}
+	}
+	return ""
+}
+
+// myIPAddress returns the address connections are made from: the
+// --bind-address, or else the interface used for outgoing traffic
+func (p *pac) myIPAddress() string {
+	if p.dialer.localAddr != nil {
+		return p.dialer.localAddr.String()
+	}
+	// Connecting a UDP socket sends nothing but picks the outgoing interface
+	conn, err := net.Dial("udp", "192.0.2.1:80")
+	if err != nil {
+		return "127.0.0.1"
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String()
+}
+
+// forRequest is the http.Transport Proxy function. It takes the first
+// entry of the PAC result that is DIRECT or a reachable proxy.
+func (p *pac) forRequest(req *http.Request) (*url.URL, error) {
+	// Like browsers, only give the script the origin of https URLs
+	target := req.URL.String()
+	if req.URL.Scheme == "https" {
+		target = "https://" + req.URL.Host + "/"
+	}
+
+	result, err := p.findProxy(target, req.URL.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("proxy auto-config failed: %v", err)
+	}
+
+	chain, err := p.parseResult(result)
+	if err != nil {
+		return nil, err
+	}
+	for i, proxy := range chain {
+		if proxy == nil || i == len(chain)-1 || p.reachable(proxy.Host) {
+			return proxy, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseResult turns a FindProxyForURL result such as
+// "PROXY a:3128; SOCKS5 b:1080; DIRECT" into proxy URLs, nil meaning DIRECT
+func (p *pac) parseResult(result string) ([]*url.URL, error) {
+	var chain []*url.URL
+	for _, entry := range strings.Split(result, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.EqualFold(fields[0], "DIRECT") {
+			chain = append(chain, nil)
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid proxy auto-config result %q", result)
+		}
+
+		var scheme string
+		switch strings.ToUpper(fields[0]) {
+		case "PROXY", "HTTP":
+			scheme = "http"
+		case "HTTPS":
+			scheme = "https"
+		case "SOCKS5":
+			scheme = "socks5"
+		default:
+			// SOCKS, which browsers take as SOCKS4, and anything else we
+			// can't speak
+			continue
+		}
+		proxy, err := parseProxy(scheme+"://"+fields[1], p.user, p.password)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, proxy)
+	}
+	if len(chain) == 0 {
+		// An empty result means DIRECT
+		chain = append(chain, nil)
+	}
+	return chain, nil
+}
+
+// reachable reports whether a TCP connection to the proxy at hostport can
+// be opened. Answers are remembered for proxyRecheck.
+func (p *pac) reachable(hostport string) bool {
+	p.mu.Lock()
+	check, ok := p.checked[hostport]
+	p.mu.Unlock()
+	if ok && time.Since(check.when) < proxyRecheck {
+		return check.up
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pacTimeout)
+	defer cancel()
+	conn, err := p.dialer.DialContext(ctx, "tcp", hostport)
+	if err == nil {
+		conn.Close()
+	}
+
+	p.mu.Lock()
+	p.checked[hostport] = proxyCheck{up: err == nil, when: time.Now()}
+	p.mu.Unlock()
+	return err == nil
+}
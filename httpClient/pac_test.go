@@ -0,0 +1,178 @@
+package httpClient
+
+import (
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPAC sends example.com through a proxy and keeps slow.test busy for
+// a while before answering
+const testPAC = `
+function FindProxyForURL(url, host) {
+	if (host == "slow.test") {
+		var end = Date.now() + 500;
+		while (Date.now() < end) {}
+	}
+	if (dnsDomainIs(host, "example.com")) return "PROXY proxy.test:3128; DIRECT";
+	return "DIRECT";
+}
+`
+
+func TestPACFetchedWithConfiguredTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, testPAC)
+	}))
+	defer server.Close()
+
+	// Only the --ca-certificate file makes the server trusted
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(ca, pem.EncodeToMemory(block), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{ProxyPAC: server.URL + "/proxy.pac", NetrcFile: filepath.Join(t.TempDir(), "netrc"), NoHSTS: true}
+	if _, err := New(opts); err == nil {
+		t.Fatal("expected the PAC fetch to fail without the CA")
+	}
+	opts.CACertificate = ca
+	c, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	proxy, err := c.transport.Proxy(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The proxy isn't reachable, so the chain falls back to DIRECT
+	if proxy != nil {
+		t.Errorf("proxy %v, want DIRECT after the unreachable proxy", proxy)
+	}
+}
+
+func TestPACConcurrentCalls(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "proxy.pac")
+	os.WriteFile(file, []byte(testPAC), 0644)
+	p, err := loadPAC(file, "", "", nil, &dialer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slow := make(chan error)
+	go func() {
+		_, err := p.findProxy("http://slow.test/", "slow.test")
+		slow <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// A slow script call must not hold up the others
+	start := time.Now()
+	result, err := p.findProxy("https://www.example.com/", "www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("call took %v while another was running", elapsed)
+	}
+	if result != "PROXY proxy.test:3128; DIRECT" {
+		t.Errorf("result %q", result)
+	}
+	if err := <-slow; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPACParseResult(t *testing.T) {
+	p := &pac{user: "u", password: "p"}
+	tests := []struct {
+		result string
+		want   []string
+	}{
+		{"DIRECT", []string{""}},
+		{"", []string{""}},
+		{"PROXY a:3128; SOCKS5 b:1080; DIRECT", []string{"http://u:p@a:3128", "socks5://u:p@b:1080", ""}},
+		{"HTTPS c:443; SOCKS4 d:1080", []string{"https://u:p@c:443"}},
+		// Browsers take a plain SOCKS entry as SOCKS4, which isn't supported
+		{"SOCKS e:1080; DIRECT", []string{""}},
+	}
+	for _, tt := range tests {
+		chain, err := p.parseResult(tt.result)
+		if err != nil {
+			t.Errorf("%q: %v", tt.result, err)
+			continue
+		}
+		var got []string
+		for _, proxy := range chain {
+			got = append(got, urlString(proxy))
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: chain %q, want %q", tt.result, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q: chain %q, want %q", tt.result, got, tt.want)
+				break
+			}
+		}
+	}
+	if _, err := p.parseResult("PROXY"); err == nil {
+		t.Error("expected an error for a PROXY entry without an address")
+	}
+}
+
+func TestPACUsesConfiguredDialer(t *testing.T) {
+	// The proxy only exists under the --resolve override
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	file := filepath.Join(t.TempDir(), "proxy.pac")
+	script := `function FindProxyForURL(url, host) {
+		if (host == "resolve.test") return "PROXY " + dnsResolve("pac-proxy.test") + ":` + port + `";
+		if (host == "myip.test") return "PROXY " + myIpAddress() + ":1";
+		return "PROXY pac-proxy.test:` + port + `; PROXY backup.test:1";
+	}`
+	os.WriteFile(file, []byte(script), 0644)
+
+	d, err := newDialer(Options{Resolve: []string{"pac-proxy.test:" + port + ":127.0.0.1"}, BindAddress: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := loadPAC(file, "", "", nil, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://resolve.test/", "http://127.0.0.1:" + port},
+		{"http://myip.test/", "http://127.0.0.1:1"},
+		// Reachable only through the override, so the backup isn't used
+		{"http://other.test/", "http://pac-proxy.test:" + port},
+	}
+	for _, tt := range tests {
+		proxy, err := p.forRequest(httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+			continue
+		}
+		if got := urlString(proxy); got != tt.want {
+			t.Errorf("%s: proxy %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
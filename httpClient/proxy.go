@@ -0,0 +1,154 @@
+package httpClient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// proxies picks the proxy for each request from --proxy or the
+// http_proxy, https_proxy and no_proxy environment variables
+type proxies struct {
+	http, https *url.URL
+	noProxy     []string
+}
+
+// proxyFunc returns the http.Transport Proxy function for opts, or nil to
+// always connect directly. --no-proxy wins over --proxy-pac, which wins over
+// --proxy and the environment. A PAC URL is fetched with pacClient; the
+// script's lookups and proxy checks go through dialer.
+func proxyFunc(opts Options, pacClient *http.Client, dialer *dialer) (func(*http.Request) (*url.URL, error), error) {
+	switch {
+	case opts.NoProxy:
+		return nil, nil
+	case opts.ProxyPAC != "":
+		p, err := loadPAC(opts.ProxyPAC, opts.ProxyUser, opts.ProxyPassword, pacClient, dialer)
+		if err != nil {
+			return nil, err
+		}
+		return p.forRequest, nil
+	}
+
+	p, err := newProxies(opts)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	return p.forRequest, nil
+}
+
+// newProxies reads --proxy and the proxy environment variables. It returns
+// nil when no proxy is configured.
+func newProxies(opts Options) (*proxies, error) {
+	httpProxy, httpsProxy := getenv("http_proxy"), getenv("https_proxy")
+	if opts.Proxy != "" {
+		httpProxy, httpsProxy = opts.Proxy, opts.Proxy
+	}
+	if httpProxy == "" && httpsProxy == "" {
+		return nil, nil
+	}
+
+	p := &proxies{}
+	var err error
+	if p.http, err = parseProxy(httpProxy, opts.ProxyUser, opts.ProxyPassword); err != nil {
+		return nil, err
+	}
+	if p.https, err = parseProxy(httpsProxy, opts.ProxyUser, opts.ProxyPassword); err != nil {
+		return nil, err
+	}
+	for _, entry := range strings.Split(getenv("no_proxy"), ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			p.noProxy = append(p.noProxy, entry)
+		}
+	}
+	return p, nil
+}
+
+// getenv reads a proxy variable, preferring the lower-case spelling
+func getenv(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToUpper(name))
+}
+
+// parseProxy parses a proxy address. "host:port" means an HTTP proxy;
+// socks5:// and socks5h:// proxies resolve host names on the proxy side.
+// --proxy-user and --proxy-password replace credentials in the URL.
+func parseProxy(raw, user, password string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q", raw)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if user != "" {
+		u.User = url.UserPassword(user, password)
+	}
+	return u, nil
+}
+
+// forRequest is the http.Transport Proxy function
+func (p *proxies) forRequest(req *http.Request) (*url.URL, error) {
+	if p.bypass(req.URL) {
+		return nil, nil
+	}
+	if req.URL.Scheme == "https" {
+		return p.https, nil
+	}
+	return p.http, nil
+}
+
+// bypass reports whether no_proxy says to connect to u directly. Entries
+// are domain names (matching subdomains too), IP addresses or CIDR ranges,
+// each optionally with a port; "*" turns the proxy off for every host.
+func (p *proxies) bypass(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range p.noProxy {
+		if entry == "*" {
+			return true
+		}
+		if h, entryPort, err := net.SplitHostPort(entry); err == nil {
+			if entryPort != port {
+				continue
+			}
+			entry = h
+		}
+		entry = strings.Trim(entry, "[]")
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if entryIP := net.ParseIP(entry); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		domain := strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package httpClient
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setProxyEnv replaces the proxy variables of the test's environment
+func setProxyEnv(t *testing.T, httpProxy, httpsProxy, noProxy string) {
+	t.Helper()
+	for name, value := range map[string]string{"http_proxy": httpProxy, "https_proxy": httpsProxy, "no_proxy": noProxy} {
+		t.Setenv(name, value)
+		t.Setenv(strings.ToUpper(name), "")
+	}
+}
+
+func TestProxySelection(t *testing.T) {
+	tests := []struct {
+		name                  string
+		httpProxy, httpsProxy string
+		option                string
+		url                   string
+		want                  string
+	}{
+		{"http from http_proxy", "proxy.local:3128", "secure.local:8443", "", "http://example.com/", "http://proxy.local:3128"},
+		{"https from https_proxy", "proxy.local:3128", "secure.local:8443", "", "https://example.com/", "http://secure.local:8443"},
+		{"https without https_proxy", "proxy.local:3128", "", "", "https://example.com/", ""},
+		{"--proxy wins", "proxy.local:3128", "secure.local:8443", "socks5h://tor.local:9050", "https://example.com/", "socks5h://tor.local:9050"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setProxyEnv(t, tt.httpProxy, tt.httpsProxy, "")
+			p, err := newProxies(Options{Proxy: tt.option})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			got, err := p.forRequest(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotURL := urlString(got); gotURL != tt.want {
+				t.Errorf("proxy %q, want %q", gotURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyNone(t *testing.T) {
+	setProxyEnv(t, "", "", "")
+	if p, err := newProxies(Options{}); p != nil || err != nil {
+		t.Errorf("newProxies() = %v, %v; want no proxy", p, err)
+	}
+	if _, err := newProxies(Options{Proxy: "ftp://proxy.local"}); err == nil {
+		t.Error("expected an unsupported scheme to be rejected")
+	}
+}
+
+func TestNoProxy(t *testing.T) {
+	tests := []struct {
+		noProxy string
+		url     string
+		bypass  bool
+	}{
+		{"example.com", "http://example.com/", true},
+		{"example.com", "http://www.example.com/", true},
+		{"example.com", "http://notexample.com/", false},
+		{".example.com", "http://a.b.example.com/", true},
+		{"*.example.com", "http://a.example.com/", true},
+		{"EXAMPLE.com", "http://Example.COM/", true},
+		{"10.0.0.0/8", "http://10.1.2.3/", true},
+		{"10.0.0.0/8", "http://11.1.2.3/", false},
+		{"10.0.0.0/8", "http://ten.example/", false},
+		{"192.168.1.5", "http://192.168.1.5:8080/", true},
+		{"fd00::/8", "http://[fd00::1]/", true},
+		{"[::1]", "http://[::1]:8080/", true},
+		{"example.com:8080", "http://example.com:8080/", true},
+		{"example.com:8080", "http://example.com/", false},
+		{"example.com:443", "https://example.com/", true},
+		{"10.0.0.0/8:80", "http://10.0.0.1/", true},
+		{"other.org, example.com", "http://example.com/", true},
+		{"*", "http://anything.example/", true},
+		{"", "http://example.com/", false},
+	}
+	for _, tt := range tests {
+		setProxyEnv(t, "proxy.local:3128", "", tt.noProxy)
+		p, err := newProxies(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, _ := url.Parse(tt.url)
+		if got := p.bypass(u); got != tt.bypass {
+			t.Errorf("no_proxy=%q, %s: bypass = %v, want %v", tt.noProxy, tt.url, got, tt.bypass)
+		}
+	}
+}
+
+func TestRequestThroughProxy(t *testing.T) {
+	// A forward proxy receives the absolute URL and answers for the origin
+	var seen *http.Request
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r
+		io.WriteString(w, "proxied "+r.URL.String())
+	}))
+	defer proxy.Close()
+
+	setProxyEnv(t, "", "", "")
+	c, err := New(Options{
+		Proxy:         proxy.URL,
+		ProxyUser:     "alice",
+		ProxyPassword: "secret",
+		NetrcFile:     filepath.Join(t.TempDir(), "netrc"),
+		NoHSTS:        true,
+		NoCookies:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.Get("http://origin.invalid/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if got, want := string(body), "proxied http://origin.invalid/file.txt"; got != want {
+		t.Errorf("body %q, want %q", got, want)
+	}
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:secret"))
+	if got := seen.Header.Get("Proxy-Authorization"); got != want {
+		t.Errorf("Proxy-Authorization %q, want %q", got, want)
+	}
+}
+
+func urlString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,60 @@
+package httpClient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NewRequest builds a request for url. The body is bodyData, or the
+// contents of bodyFile, and gets a Content-Type guessed from it; --header
+// can still replace that. The body can be sent again for authentication
+// retries and 307/308 redirects.
+func NewRequest(method, url, bodyData, bodyFile string) (*http.Request, error) {
+	if method == "" {
+		method = http.MethodGet
+	}
+	if bodyFile == "" {
+		var body io.Reader
+		if bodyData != "" {
+			body = strings.NewReader(bodyData)
+		}
+		req, err := http.NewRequest(method, url, body)
+		if err != nil {
+			return nil, err
+		}
+		if bodyData != "" {
+			req.Header.Set("Content-Type", contentType([]byte(bodyData), ""))
+		}
+		return req, nil
+	}
+
+	// Read the file once up front so a missing file fails before connecting
+	data, err := os.ReadFile(bodyFile)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType(data, bodyFile))
+	return req, nil
+}
+
+// contentType guesses the type of a request body: from the file name when
+// there is one, JSON if it parses as JSON, and form data like wget otherwise
+func contentType(body []byte, file string) string {
+	if t := mime.TypeByExtension(filepath.Ext(file)); file != "" && t != "" {
+		return t
+	}
+	if json.Valid(body) {
+		return "application/json"
+	}
+	return "application/x-www-form-urlencoded"
+}
@@ -0,0 +1,220 @@
+package httpClient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"wget/exitStatus"
+)
+
+// newTLSConfig builds the TLS settings from --ca-certificate,
+// --ca-directory, --certificate, --private-key, --no-check-certificate,
+// --secure-protocol and --pinnedpubkey
+func newTLSConfig(opts Options) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: opts.NoCheckCertificate}
+
+	switch strings.ToLower(opts.SecureProtocol) {
+	case "", "auto":
+		config.MinVersion = tls.VersionTLS12
+	case "tlsv1_2":
+		config.MinVersion = tls.VersionTLS12
+	case "tlsv1_3":
+		config.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported secure protocol %q (use auto, TLSv1_2 or TLSv1_3)", opts.SecureProtocol)
+	}
+
+	// Private CAs are trusted in addition to the system ones
+	if opts.CACertificate != "" || opts.CADirectory != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if opts.CACertificate != "" {
+			if err := addCAFile(pool, opts.CACertificate); err != nil {
+				return nil, err
+			}
+		}
+		if opts.CADirectory != "" {
+			if err := addCADirectory(pool, opts.CADirectory); err != nil {
+				return nil, err
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.Certificate != "" {
+		key := opts.PrivateKey
+		if key == "" {
+			// The key may be in the same PEM file as the certificate
+			key = opts.Certificate
+		}
+		cert, err := tls.LoadX509KeyPair(opts.Certificate, key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	} else if opts.PrivateKey != "" {
+		return nil, errors.New("--private-key needs --certificate")
+	}
+
+	if opts.PinnedPubKey != "" {
+		pins, err := parsePins(opts.PinnedPubKey)
+		if err != nil {
+			return nil, err
+		}
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return checkPins(state, pins)
+		}
+	}
+	return config, nil
+}
+
+// addCAFile adds the PEM certificates in file to pool
+func addCAFile(pool *x509.CertPool, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("cannot read CA certificate: %v", err)
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no certificates found in %s", file)
+	}
+	return nil
+}
+
+// addCADirectory adds every PEM certificate found in dir to pool, like
+// OpenSSL's hashed certificate directories (c_rehash)
+func addCADirectory(pool *x509.CertPool, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("cannot read CA directory: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		pool.AppendCertsFromPEM(data)
+	}
+	return nil
+}
+
+// parsePins reads --pinnedpubkey: either "sha256//<base64>" hashes separated
+// by ';', or a file holding the public key in PEM or DER form
+func parsePins(value string) ([][]byte, error) {
+	var pins [][]byte
+	if !strings.HasPrefix(value, "sha256//") {
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read pinned public key: %v", err)
+		}
+		if block, _ := pem.Decode(data); block != nil {
+			data = block.Bytes
+		}
+		sum := sha256.Sum256(data)
+		return [][]byte{sum[:]}, nil
+	}
+
+	for _, pin := range strings.Split(value, ";") {
+		hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(pin), "sha256//"))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid pinned public key %q (expected sha256//<base64>)", pin)
+		}
+		pins = append(pins, hash)
+	}
+	return pins, nil
+}
+
+// checkPins fails the handshake unless the server's public key matches a pin
+func checkPins(state tls.ConnectionState, pins [][]byte) error {
+	if len(state.PeerCertificates) == 0 {
+		return exitStatus.Wrap(exitStatus.SSL, errors.New("public key pinning failed: no server certificate"))
+	}
+	sum := sha256.Sum256(state.PeerCertificates[0].RawSubjectPublicKeyInfo)
+	for _, pin := range pins {
+		if bytes.Equal(sum[:], pin) {
+			return nil
+		}
+	}
+	return exitStatus.Wrap(exitStatus.SSL, fmt.Errorf("public key pinning failed: %s has key sha256//%s",
+		state.ServerName, base64.StdEncoding.EncodeToString(sum[:])))
+}
+
+// diagnoseTLS explains certificate and handshake failures and gives them
+// the SSL exit status. Other errors are returned unchanged.
+func diagnoseTLS(err error, host string) error {
+	if err == nil {
+		return nil
+	}
+
+	var (
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		recordErr    tls.RecordHeaderError
+		exitErr      *exitStatus.Error
+		opErr        *net.OpError
+	)
+	var reason string
+	switch {
+	case errors.As(err, &exitErr):
+		return err
+	case errors.As(err, &authorityErr):
+		reason = fmt.Sprintf("cannot verify %s's certificate: it is issued by an unknown authority (%s); "+
+			"use --ca-certificate to trust a private CA or --no-check-certificate to skip the check",
+			host, issuer(authorityErr.Cert))
+	case errors.As(err, &hostnameErr):
+		reason = fmt.Sprintf("the certificate of %s is not valid for that name (it covers %s)",
+			host, strings.Join(certNames(hostnameErr.Certificate), ", "))
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		cert := invalidErr.Cert
+		reason = fmt.Sprintf("the certificate of %s is only valid from %s to %s", host,
+			cert.NotBefore.Format(time.DateOnly), cert.NotAfter.Format(time.DateOnly))
+	case errors.As(err, &invalidErr):
+		reason = fmt.Sprintf("the certificate chain of %s is invalid", host)
+	case errors.As(err, &recordErr):
+		reason = fmt.Sprintf("%s did not answer with TLS (it may be a plain http:// server)", host)
+	case errors.As(err, &opErr) && opErr.Op == "remote error":
+		// crypto/tls reports an alert from the server this way
+		reason = fmt.Sprintf("%s refused the TLS handshake (it may require a client certificate or another --secure-protocol)", host)
+	default:
+		return err
+	}
+	return exitStatus.Wrap(exitStatus.SSL, fmt.Errorf("%s: %w", reason, err))
+}
+
+// issuer names the certificate authority that signed cert
+func issuer(cert *x509.Certificate) string {
+	if cert == nil || cert.Issuer.CommonName == "" {
+		return "unnamed issuer"
+	}
+	return cert.Issuer.CommonName
+}
+
+// certNames lists the host names a certificate is valid for
+func certNames(cert *x509.Certificate) []string {
+	if cert == nil {
+		return nil
+	}
+	names := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if len(names) == 0 && cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	return names
+}
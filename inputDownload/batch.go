@@ -3,29 +3,57 @@ package inputDownload
 import (
 	"bufio"
 	"fmt"
-	"log"
 	"os"
+	"strings"
 	"sync"
-	"wget/fileDownload"
+	"wget/exitStatus"
+	"wget/job"
 )
 
-// Start handles downloading multiple files listed in the input file.
-func Start(inputFile string) {
+// Start handles downloading multiple files listed in the input file. Each
+// URL is handed to run together with cfg, so the rest of the options apply
+// to every line. The returned error carries the combined exit status of all
+// failed downloads.
+func Start(cfg *job.Config, run job.Runner) error {
+	logger := cfg.Logger
+
 	// Open the input file
-	file, err := os.Open(inputFile)
+	file, err := os.Open(cfg.InputFile)
 	if err != nil {
-		log.Fatalf("Error opening file %s: %v", inputFile, err)
+		return exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("error opening file %s: %v", cfg.InputFile, err))
 	}
 	defer file.Close()
 
 	// Read URLs from the input file
 	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed int
+	status := exitStatus.Success
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		url := scanner.Text()
+		url := strings.TrimSpace(scanner.Text())
+		if url == "" {
+			continue
+		}
 
 		// Notify the user about the download starting asynchronously
-		fmt.Printf("Starting download for: %s\n", url)
+		logger.Info("Starting download", "url", url)
+
+		fail := func(err error) {
+			mu.Lock()
+			failed++
+			status = exitStatus.Combine(status, exitStatus.Of(err))
+			mu.Unlock()
+		}
+
+		// Downloads into one shared document (-O) run in order so their
+		// bodies don't interleave
+		if cfg.Document != nil {
+			if err := download(cfg, run, url); err != nil {
+				fail(err)
+			}
+			continue
+		}
 
 		// Add the download task to the WaitGroup
 		wg.Add(1)
@@ -33,20 +61,36 @@ func Start(inputFile string) {
 		// Start the download in a goroutine
 		go func(url string) {
 			defer wg.Done()
-
-			// Call the download function (fileDownload.Start handles the actual download)
-			fileDownload.Start(url, "", "")
-			if err != nil {
-				log.Printf("Error downloading %s: %v", url, err)
-			} else {
-				fmt.Printf("Download complete: %s\n", url)
+			if err := download(cfg, run, url); err != nil {
+				fail(err)
 			}
 		}(url)
 	}
 
 	// Wait for all downloads to finish
 	wg.Wait()
+	if err := scanner.Err(); err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, err)
+	}
 
 	// Notify the user once all downloads are complete
-	fmt.Println("All downloads complete.")
+	logger.Info("All downloads complete")
+	if failed > 0 {
+		return exitStatus.Wrap(status, fmt.Errorf("%d downloads failed", failed))
+	}
+	return nil
+}
+
+// download runs a single line from the list and reports how it went
+func download(cfg *job.Config, run job.Runner, line string) error {
+	lineCfg, url, err := parseLine(cfg, line)
+	if err == nil {
+		err = run(lineCfg, url)
+	}
+	if err != nil {
+		cfg.Logger.Error("Error downloading", "url", url, "err", err)
+	} else {
+		cfg.Logger.Info("Download complete", "url", url)
+	}
+	return err
 }
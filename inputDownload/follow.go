@@ -0,0 +1,268 @@
+package inputDownload
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"wget/exitStatus"
+	"wget/job"
+)
+
+// followWorkers is the number of downloads run at the same time in --follow mode
+const followWorkers = 4
+
+// pollInterval is how often the input file is checked for new lines
+var pollInterval = time.Second
+
+// Follow keeps the input file open and downloads URLs as they are appended
+// to it, like tail -f. URLs that finished successfully are recorded in
+// the input file name plus ".done" so they are skipped after a restart. It
+// stops on SIGINT or SIGTERM, or when cfg.Context ends, cancelling the
+// downloads in progress; they are fetched again after a restart. A second
+// signal kills it at once. The returned error carries the combined exit
+// status of all failed downloads.
+func Follow(cfg *job.Config, run job.Runner) error {
+	logger := cfg.Logger
+	inputFile := cfg.InputFile
+
+	file, err := os.Open(inputFile)
+	if err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("error opening file %s: %v", inputFile, err))
+	}
+
+	done, err := openDoneList(inputFile + ".done")
+	if err != nil {
+		return err
+	}
+	defer done.Close()
+
+	parent := cfg.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// Let the next signal end the process the usual way
+		<-ctx.Done()
+		stop()
+	}()
+	followCfg := *cfg
+	followCfg.Context = ctx
+	cfg = &followCfg
+
+	// Start the worker pool; a shared -O document takes one download at a time
+	workers := followWorkers
+	if cfg.Document != nil {
+		workers = 1
+	}
+	urls := make(chan string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed int
+	status := exitStatus.Success
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range urls {
+				if err := download(cfg, run, url); err != nil {
+					// A failed URL is tried again when it is appended again
+					done.release(url)
+					mu.Lock()
+					failed++
+					status = exitStatus.Combine(status, exitStatus.Of(err))
+					mu.Unlock()
+					continue
+				}
+				done.add(url)
+			}
+		}()
+	}
+
+	logger.Info("Following input file for new URLs (Ctrl+C to stop)", "file", inputFile)
+	err = tail(ctx, file, logger, func(url string) {
+		// Mark the URL before it finishes so a repeated line isn't queued twice
+		switch claimed, finished := done.claim(url); {
+		case finished:
+			logger.Info("Already downloaded, skipping", "url", url)
+			return
+		case !claimed:
+			logger.Info("Already queued, skipping", "url", url)
+			return
+		}
+		logger.Info("Starting download", "url", url)
+		select {
+		case urls <- url:
+		case <-ctx.Done():
+		}
+	})
+
+	close(urls)
+	logger.Info("Stopping, waiting for downloads in progress")
+	wg.Wait()
+	logger.Info("Follow mode stopped")
+	if err != nil {
+		return exitStatus.Wrap(exitStatus.FileIO, err)
+	}
+	if failed > 0 {
+		return exitStatus.Wrap(status, fmt.Errorf("%d downloads failed", failed))
+	}
+	return nil
+}
+
+// tail reads complete lines from file and passes each non-empty one to
+// handle, polling for more data until ctx is cancelled. A file that shrinks
+// is assumed to have been truncated and is read again from the start; when
+// its name comes to point at a new file, as after log rotation, the new
+// file is read from the start. tail closes the files it reads.
+func tail(ctx context.Context, file *os.File, logger *slog.Logger, handle func(string)) error {
+	var next *os.File
+	defer func() {
+		file.Close()
+		if next != nil {
+			next.Close()
+		}
+	}()
+	reader := bufio.NewReader(file)
+	var offset int64
+	var partial string
+
+	for {
+		line, err := reader.ReadString('\n')
+		offset += int64(len(line))
+		if err == nil {
+			if url := strings.TrimSpace(partial + line); url != "" {
+				handle(url)
+			}
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+
+		// Keep an unfinished last line until the writer adds its newline
+		partial += line
+
+		if next != nil {
+			// The old file is finished, including a last line without newline
+			if url := strings.TrimSpace(partial); url != "" {
+				handle(url)
+			}
+			file.Close()
+			file, next = next, nil
+			reader.Reset(file)
+			offset, partial = 0, ""
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+
+		if rotated, err := reopen(file); err != nil {
+			return err
+		} else if rotated != nil {
+			// Read what was added to the old file before switching
+			logger.Warn("Input file was replaced, reading the new one", "file", file.Name())
+			next = rotated
+			continue
+		}
+
+		if info, err := file.Stat(); err == nil && info.Size() < offset {
+			logger.Warn("Input file was truncated, reading from the start", "file", file.Name())
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			reader.Reset(file)
+			offset, partial = 0, ""
+		}
+	}
+}
+
+// reopen opens the file now found at the name of file when it is a
+// different one. It returns nil while the name still points at file or at
+// nothing, e.g. between renaming the old file and creating the new one.
+func reopen(file *os.File) (*os.File, error) {
+	current, err := os.Stat(file.Name())
+	if err != nil {
+		return nil, nil
+	}
+	info, err := file.Stat()
+	if err != nil || os.SameFile(info, current) {
+		return nil, err
+	}
+	return os.Open(file.Name())
+}
+
+// doneList is the set of URLs already handled in --follow mode, backed by a file
+type doneList struct {
+	mu      sync.Mutex
+	file    *os.File
+	urls    map[string]bool
+	pending map[string]bool
+}
+
+// openDoneList loads the URLs recorded in path and opens it for appending
+func openDoneList(path string) (*doneList, error) {
+	d := &doneList{urls: make(map[string]bool), pending: make(map[string]bool)}
+
+	if data, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				d.urls[line] = true
+			}
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("error opening %s: %v", path, err))
+	}
+	d.file = file
+	return d, nil
+}
+
+// claim marks url as queued for this run. It reports false instead when
+// url is already queued, or finished as the second result says.
+func (d *doneList) claim(url string) (claimed, finished bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.urls[url] || d.pending[url] {
+		return false, d.urls[url]
+	}
+	d.pending[url] = true
+	return true, false
+}
+
+// release gives up the claim on url after its download failed
+func (d *doneList) release(url string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, url)
+}
+
+// add records url as finished so it survives a restart
+func (d *doneList) add(url string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, url)
+	d.urls[url] = true
+	fmt.Fprintln(d.file, url)
+}
+
+// Close closes the underlying file
+func (d *doneList) Close() error {
+	return d.file.Close()
+}
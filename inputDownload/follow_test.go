@@ -0,0 +1,235 @@
+package inputDownload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wget/exitStatus"
+	"wget/job"
+)
+
+func init() {
+	pollInterval = 10 * time.Millisecond
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// appendTo adds text to the end of file
+func appendTo(t *testing.T, file, text string) {
+	t.Helper()
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor polls until cond holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for end := time.Now().Add(5 * time.Second); time.Now().Before(end); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// tailer runs tail on a file in the background and collects its lines
+type tailer struct {
+	mu    sync.Mutex
+	lines []string
+	stop  context.CancelFunc
+	err   chan error
+}
+
+func startTail(t *testing.T, file string) *tailer {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	tl := &tailer{stop: stop, err: make(chan error, 1)}
+	go func() {
+		tl.err <- tail(ctx, f, discardLogger, func(line string) {
+			tl.mu.Lock()
+			tl.lines = append(tl.lines, line)
+			tl.mu.Unlock()
+		})
+	}()
+	t.Cleanup(func() {
+		stop()
+		<-tl.err
+	})
+	return tl
+}
+
+// expect waits until the lines seen so far are want
+func (tl *tailer) expect(t *testing.T, want ...string) {
+	t.Helper()
+	got := func() string {
+		tl.mu.Lock()
+		defer tl.mu.Unlock()
+		return strings.Join(tl.lines, " ")
+	}
+	for end := time.Now().Add(5 * time.Second); time.Now().Before(end); time.Sleep(5 * time.Millisecond) {
+		if got() == strings.Join(want, " ") {
+			return
+		}
+	}
+	t.Fatalf("lines %q, want %q", got(), strings.Join(want, " "))
+}
+
+func TestTail(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "urls.txt")
+	appendTo(t, file, "a\n\n  b  \nc")
+	tl := startTail(t, file)
+
+	// The unfinished last line waits for its newline
+	tl.expect(t, "a", "b")
+	time.Sleep(3 * pollInterval)
+	tl.expect(t, "a", "b")
+	appendTo(t, file, "d\ne\n")
+	tl.expect(t, "a", "b", "cd", "e")
+}
+
+func TestTailTruncated(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "urls.txt")
+	appendTo(t, file, "a\nb\n")
+	tl := startTail(t, file)
+	tl.expect(t, "a", "b")
+
+	if err := os.WriteFile(file, []byte("c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tl.expect(t, "a", "b", "c")
+	appendTo(t, file, "d\n")
+	tl.expect(t, "a", "b", "c", "d")
+}
+
+func TestTailRotated(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "urls.txt")
+	appendTo(t, file, "a\n")
+	tl := startTail(t, file)
+	tl.expect(t, "a")
+
+	// Lines added before the rename and an unfinished last line are kept
+	appendTo(t, file, "b\nc")
+	if err := os.Rename(file, filepath.Join(dir, "urls.txt.1")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(3 * pollInterval)
+	appendTo(t, file, "d\n")
+	tl.expect(t, "a", "b", "c", "d")
+	appendTo(t, file, "e\n")
+	tl.expect(t, "a", "b", "c", "d", "e")
+}
+
+func TestDoneList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt.done")
+	os.WriteFile(path, []byte("http://old/\n"), 0644)
+	done, err := openDoneList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		do                string
+		url               string
+		claimed, finished bool
+	}{
+		{"claim", "http://old/", false, true},
+		{"claim", "http://a/", true, false},
+		{"claim", "http://a/", false, false},
+		{"release", "http://a/", false, false},
+		{"claim", "http://a/", true, false},
+		{"add", "http://a/", false, false},
+		{"claim", "http://a/", false, true},
+	}
+	for i, step := range steps {
+		switch step.do {
+		case "claim":
+			claimed, finished := done.claim(step.url)
+			if claimed != step.claimed || finished != step.finished {
+				t.Errorf("step %d: claim(%s) = %v, %v, want %v, %v", i, step.url, claimed, finished, step.claimed, step.finished)
+			}
+		case "release":
+			done.release(step.url)
+		case "add":
+			done.add(step.url)
+		}
+	}
+	done.Close()
+
+	// Only finished URLs are recorded for a restart
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "http://old/\nhttp://a/\n" {
+		t.Errorf(".done file %q", data)
+	}
+}
+
+func TestFollowRetriesFailedURL(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "urls.txt")
+	appendTo(t, file, "http://a/\n")
+
+	var mu sync.Mutex
+	var calls []string
+	failing := true
+	run := func(cfg *job.Config, url string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, url)
+		if url == "http://a/" && failing {
+			failing = false
+			return exitStatus.Wrap(exitStatus.ServerError, errors.New("404 Not Found"))
+		}
+		return nil
+	}
+	callCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(calls)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- Follow(&job.Config{InputFile: file, Follow: true, Context: ctx, Logger: discardLogger}, run)
+	}()
+
+	waitFor(t, "the first attempt", func() bool { return callCount() == 1 })
+	// The failed URL is tried again when it is appended again; a finished one isn't
+	appendTo(t, file, "http://a/\n")
+	waitFor(t, "the second attempt", func() bool { return callCount() == 2 })
+	appendTo(t, file, "http://a/\nhttp://b/\n")
+	waitFor(t, "http://b/", func() bool { return callCount() == 3 })
+	time.Sleep(5 * pollInterval)
+	stop()
+
+	err := <-result
+	if code := exitStatus.Of(err); code != exitStatus.ServerError {
+		t.Errorf("exit status %d (%v), want %d", code, err, exitStatus.ServerError)
+	}
+	if got := strings.Join(calls, " "); got != "http://a/ http://a/ http://b/" {
+		t.Errorf("downloads %q", got)
+	}
+	data, _ := os.ReadFile(file + ".done")
+	if string(data) != "http://a/\nhttp://b/\n" {
+		t.Errorf(".done file %q", data)
+	}
+}
@@ -0,0 +1,108 @@
+package inputDownload
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"wget/exitStatus"
+	"wget/job"
+	"wget/options"
+)
+
+// parseLine splits an input file line into its URL and the configuration to
+// download it with. A line may carry its own request options after the URL,
+// e.g. "https://example.com/export --post-data=format=csv"; they apply to
+// that line only. The URL is taken as it is; option values with spaces are
+// quoted as in the shell, e.g.
+// "https://example.com/a.tgz --pipe-to 'tar xz -C {name}.d'".
+func parseLine(cfg *job.Config, line string) (*job.Config, string, error) {
+	line = strings.TrimSpace(line)
+	url, rest := line, ""
+	if i := strings.IndexFunc(line, unicode.IsSpace); i >= 0 {
+		url, rest = line[:i], strings.TrimSpace(line[i:])
+	}
+	if rest == "" {
+		return cfg, url, nil
+	}
+	args, err := splitLine(rest)
+	if err != nil {
+		return nil, url, exitStatus.Wrap(exitStatus.Parse, err)
+	}
+
+	flags := options.NewParser("input line")
+	method := flags.String("method", "", "", "HTTP method")
+	postData := flags.String("post-data", "", "", "POST body")
+	postFile := flags.String("post-file", "", "", "POST body file")
+	bodyData := flags.String("body-data", "", "", "Request body")
+	bodyFile := flags.String("body-file", "", "", "Request body file")
+	pipeTo := flags.String("pipe-to", "", "", "Command that receives the body")
+	extract, extractDir := flags.Optional("extract", "Unpack the archive")
+
+	extra, err := flags.Parse(args)
+	if err == nil && len(extra) != 0 {
+		err = errors.New("expected one URL per line, followed by its options")
+	}
+	if err != nil {
+		return nil, url, exitStatus.Wrap(exitStatus.Parse, err)
+	}
+
+	lineCfg := *cfg
+	if err := lineCfg.SetRequest(*method, *postData, *postFile, *bodyData, *bodyFile); err != nil {
+		return nil, url, exitStatus.Wrap(exitStatus.Parse, err)
+	}
+	if *pipeTo != "" {
+		lineCfg.PipeTo = *pipeTo
+	}
+	if *extract {
+		lineCfg.Extract = true
+		if lineCfg.ExtractDir, err = job.ExpandHome(*extractDir); err != nil {
+			return nil, url, err
+		}
+	}
+	// The line's options must combine with the rest of the run as well
+	if err := lineCfg.Validate(); err != nil {
+		return nil, url, exitStatus.Wrap(exitStatus.Parse, err)
+	}
+	return &lineCfg, url, nil
+}
+
+// splitLine splits line into words, honoring single and double quotes and
+// backslash escapes like the shell does
+func splitLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,108 @@
+package inputDownload
+
+import (
+	"reflect"
+	"testing"
+	"wget/exitStatus"
+	"wget/job"
+)
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"  a \t b  ", []string{"a", "b"}},
+		{`--pipe-to 'tar xz -C {name}.d'`, []string{"--pipe-to", "tar xz -C {name}.d"}},
+		{`--post-data "a=1&b=two words"`, []string{"--post-data", "a=1&b=two words"}},
+		{`--body-data='{"k": "v"}'`, []string{"--body-data={\"k\": \"v\"}"}},
+		{`a\ b c`, []string{"a b", "c"}},
+		{`"say \"hi\"" 'no \escape'`, []string{`say "hi"`, `no \escape`}},
+		{`it"'"s`, []string{"it's"}},
+		{`'' ""`, []string{"", ""}},
+		{`x\\y`, []string{`x\y`}},
+	}
+	for _, tt := range tests {
+		got, err := splitLine(tt.line)
+		if err != nil {
+			t.Errorf("splitLine(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{`'open`, `"open`, `trailing\`, `a "b 'c" 'd`} {
+		if _, err := splitLine(line); err == nil {
+			t.Errorf("splitLine(%q) succeeded, want an error", line)
+		}
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	base := &job.Config{InputFile: "urls.txt", ExtractDir: "base"}
+	tests := []struct {
+		line string
+		url  string
+		// check inspects the configuration of the line
+		check func(cfg *job.Config) bool
+	}{
+		{"  https://example.com/a?x=1&y=2  ", "https://example.com/a?x=1&y=2",
+			func(cfg *job.Config) bool { return cfg == base }},
+		{"https://example.com/export --post-data=format=csv", "https://example.com/export",
+			func(cfg *job.Config) bool { return cfg.Method == "POST" && cfg.BodyData == "format=csv" }},
+		{"https://example.com/x --method put --body-data '{\"a\": 1}'", "https://example.com/x",
+			func(cfg *job.Config) bool { return cfg.Method == "PUT" && cfg.BodyData == `{"a": 1}` }},
+		{"https://example.com/a.tgz --pipe-to 'tar xz -C {name}.d'", "https://example.com/a.tgz",
+			func(cfg *job.Config) bool { return cfg.PipeTo == "tar xz -C {name}.d" }},
+		{"https://example.com/a.zip --extract=out", "https://example.com/a.zip",
+			func(cfg *job.Config) bool { return cfg.Extract && cfg.ExtractDir == "out" }},
+		{"https://example.com/a.zip --extract", "https://example.com/a.zip",
+			func(cfg *job.Config) bool { return cfg.Extract && cfg.ExtractDir == "" }},
+	}
+	for _, tt := range tests {
+		cfg, url, err := parseLine(base, tt.line)
+		if err != nil {
+			t.Errorf("parseLine(%q): %v", tt.line, err)
+			continue
+		}
+		if url != tt.url || !tt.check(cfg) {
+			t.Errorf("parseLine(%q) = %q, %+v", tt.line, url, cfg)
+		}
+	}
+	if base.Method != "" || base.PipeTo != "" || base.Extract {
+		t.Errorf("a line changed the shared configuration: %+v", base)
+	}
+}
+
+func TestParseLineErrors(t *testing.T) {
+	mirror := &job.Config{InputFile: "urls.txt", Mirror: true}
+	plain := &job.Config{InputFile: "urls.txt"}
+	tests := []struct {
+		cfg  *job.Config
+		line string
+	}{
+		// Unknown options, or ones that only make sense for the whole run
+		{plain, "https://example.com/ --mirror"},
+		{plain, "https://example.com/ --output-document=x"},
+		{plain, "https://example.com/ -q"},
+		// A second URL, unbalanced quotes, missing values
+		{plain, "https://example.com/a https://example.com/b"},
+		{plain, "https://example.com/ --post-data 'unterminated"},
+		{plain, "https://example.com/ --pipe-to"},
+		// Invalid combinations within the line or with the run
+		{plain, "https://example.com/ --post-data=a --body-data=b"},
+		{plain, "https://example.com/ --body-data=b"},
+		{plain, "https://example.com/ --method=PUT --post-data=a"},
+		{plain, "https://example.com/ --pipe-to cat --extract"},
+		{mirror, "https://example.com/ --method=DELETE"},
+	}
+	for _, tt := range tests {
+		_, _, err := parseLine(tt.cfg, tt.line)
+		if code := exitStatus.Of(err); code != exitStatus.Parse {
+			t.Errorf("parseLine(%q) = %v, want a parse error", tt.line, err)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"wget/exitStatus"
+	"wget/hooks"
+	"wget/httpClient"
+)
+
+// Config is the single description of a run, built from the command line.
+// Every mode reads its settings from here so options combine instead of
+// being dropped by whichever mode happens to run.
+type Config struct {
+	// URLs are the positional arguments
+	URLs []string
+	// InputFile lists more URLs, one per line (-i)
+	InputFile string
+	// Follow keeps reading InputFile as it grows (--follow)
+	Follow bool
+
+	// Background sends output to wget-log (-B)
+	Background bool
+	// At and Every schedule the run (--at, --every)
+	At, Every string
+
+	// RateLimit is the maximum speed in bytes per second, 0 for unlimited
+	RateLimit int64
+	// Output is the file name to save to (-O); "-" is standard output
+	Output string
+	// Document receives the body of every download when -O names one
+	// document for the whole run: standard output, or a file shared by
+	// several URLs. It is nil when each download gets its own file.
+	Document io.Writer
+	// PipeTo is a shell command that receives the body on its stdin instead
+	// of a file (--pipe-to). {url} and {name} stand for the URL and the file
+	// name it would be saved as.
+	PipeTo string
+	// Extract unpacks downloaded archives into ExtractDir, or next to the
+	// archive when it is empty (--extract[=DIR])
+	Extract    bool
+	ExtractDir string
+	// SaveDir is the directory files are saved in (-P)
+	SaveDir string
+	// NoClobber skips files that already exist (-nc)
+	NoClobber bool
+	// Timestamping only downloads files newer than the local copy
+	Timestamping bool
+
+	// Method is the HTTP method of the request (--method); empty means GET
+	Method string
+	// BodyData or the contents of BodyFile are sent as the request body
+	// (--post-data, --post-file, --body-data, --body-file)
+	BodyData, BodyFile string
+
+	// Tries is how many times a download is attempted, 0 for no limit (-t)
+	Tries int
+	// WaitRetry is the longest pause between attempts (--waitretry)
+	WaitRetry time.Duration
+	// Deadline is the time budget of each file, retries included (--deadline)
+	Deadline time.Duration
+
+	// Spider checks that URLs exist without downloading them (--spider);
+	// with Mirror it crawls the site and reports broken links
+	Spider bool
+
+	// Mirror crawls the site instead of saving a single file (--mirror)
+	Mirror bool
+	// ConvertLinks rewrites links for offline viewing (-k)
+	ConvertLinks bool
+	// Reject lists file extensions the mirror skips (-R)
+	Reject []string
+	// Exclude lists paths the mirror skips (-X)
+	Exclude []string
+
+	// ShowProgress draws a progress bar on stderr during transfers
+	ShowProgress bool
+
+	// Client sends every HTTP request of the run
+	Client *httpClient.Client
+
+	// Context stops the requests of the run early, e.g. when --follow or a
+	// schedule is interrupted; nil means they always run to the end
+	Context context.Context
+
+	// Hooks run when a download or mirror completes
+	Hooks hooks.Hooks
+	// Logger receives all output
+	Logger *slog.Logger
+}
+
+// SharesDocument reports whether the downloads of the run all go to the
+// one document named by -O, as GNU wget does with -O - or several URLs
+func (cfg *Config) SharesDocument() bool {
+	return cfg.Output == "-" || (cfg.Output != "" && (len(cfg.URLs) > 1 || cfg.InputFile != ""))
+}
+
+// Runner downloads or mirrors a single URL as described by cfg
+type Runner func(cfg *Config, url string) error
+
+// Validate rejects option combinations that cannot work together
+func (cfg *Config) Validate() error {
+	if len(cfg.URLs) == 0 && cfg.InputFile == "" {
+		return errors.New("missing URL")
+	}
+	if cfg.Follow && cfg.InputFile == "" {
+		return errors.New("--follow needs an input file (-i)")
+	}
+	if cfg.Follow && (cfg.At != "" || cfg.Every != "") {
+		return errors.New("--follow never finishes, so it can't be combined with --at or --every")
+	}
+	if cfg.Output == "-" && (cfg.Background || cfg.At != "") {
+		return errors.New("-O - can't be used with -B or --at: the background copy has no standard output")
+	}
+	if cfg.Every != "" && cfg.SharesDocument() {
+		return errors.New("--every would append every run to the same -O document; let each URL be saved to its own file instead")
+	}
+	if cfg.Mirror && cfg.Output != "" {
+		return errors.New("-O can't be used with --mirror; use -P to choose where the site is saved")
+	}
+	if cfg.PipeTo != "" && (cfg.Mirror || cfg.Output != "") {
+		return errors.New("--pipe-to can't be used with --mirror or -O")
+	}
+	if cfg.Extract && (cfg.Mirror || cfg.PipeTo != "" || cfg.SharesDocument()) {
+		return errors.New("--extract needs each download saved to its own file, so it can't be used with --mirror, --pipe-to, -O - or one -O file for several URLs")
+	}
+	if cfg.Spider && (cfg.Output != "" || cfg.PipeTo != "" || cfg.Extract || cfg.Method != "") {
+		return errors.New("--spider saves nothing and only sends HEAD requests, so it can't be used with -O, --pipe-to, --extract or --method")
+	}
+	if cfg.Mirror && cfg.Method != "" {
+		return errors.New("--method and the body options can't be used with --mirror")
+	}
+	return nil
+}
+
+// SetRequest sets the method and body from --method, --post-data,
+// --post-file, --body-data and --body-file. When none of them is given the
+// current request settings are kept.
+func (cfg *Config) SetRequest(method, postData, postFile, bodyData, bodyFile string) error {
+	bodies := 0
+	for _, body := range []string{postData, postFile, bodyData, bodyFile} {
+		if body != "" {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return errors.New("only one of --post-data, --post-file, --body-data and --body-file can be used")
+	}
+
+	method = strings.ToUpper(method)
+	switch {
+	case postData != "" || postFile != "":
+		if method != "" && method != http.MethodPost {
+			return errors.New("--post-data and --post-file always POST; use --body-data or --body-file with --method")
+		}
+		method = http.MethodPost
+	case (bodyData != "" || bodyFile != "") && method == "":
+		return errors.New("--body-data and --body-file need --method")
+	}
+	if method == "" {
+		return nil
+	}
+
+	cfg.Method = method
+	cfg.BodyData = postData + bodyData
+	cfg.BodyFile = postFile + bodyFile
+	return nil
+}
+
+// Retry runs attempt until it succeeds, fails in a way that trying again
+// won't fix, or the tries run out. After the n-th failure it waits n
+// seconds, at most WaitRetry. The context given to attempt ends when the
+// deadline of the file is used up or cfg.Context is cancelled.
+func (cfg *Config) Retry(url string, attempt func(ctx context.Context) error) error {
+	ctx := cfg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent := ctx
+	if cfg.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Deadline)
+		defer cancel()
+	}
+
+	for try := 1; ; try++ {
+		err := attempt(ctx)
+		if err == nil {
+			return nil
+		}
+		if parent.Err() != nil {
+			return fmt.Errorf("interrupted: %w", parent.Err())
+		}
+		if ctx.Err() != nil {
+			return exitStatus.Wrap(exitStatus.Timeout, fmt.Errorf("deadline of %v exceeded: %w", cfg.Deadline, err))
+		}
+		if !exitStatus.Retryable(err) || (cfg.Tries > 0 && try >= cfg.Tries) {
+			return err
+		}
+
+		wait := min(time.Duration(try)*time.Second, cfg.WaitRetry)
+		cfg.Logger.Warn("Retrying", "url", url, "try", try+1, "wait", wait, "err", err)
+		select {
+		case <-time.After(wait):
+		case <-parent.Done():
+			return fmt.Errorf("interrupted: %w", parent.Err())
+		case <-ctx.Done():
+			return exitStatus.Wrap(exitStatus.Timeout, fmt.Errorf("deadline of %v exceeded: %w", cfg.Deadline, err))
+		}
+	}
+}
+
+// Mode names the kind of run for logs and completion hooks
+func (cfg *Config) Mode() string {
+	switch {
+	case cfg.Spider:
+		return "spider"
+	case cfg.Mirror:
+		return "mirror"
+	case cfg.InputFile != "":
+		return "batch"
+	case cfg.Background:
+		return "background"
+	}
+	return "file"
+}
+
+// ExpandHome replaces a leading "~" in path with the user's home directory,
+// for directories given in a wgetrc file or quoted on the command line,
+// e.g. "-P '~/downloads'". "~user" forms are left as they are.
+func ExpandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("cannot expand %s: %v", path, err))
+	}
+	return filepath.Join(home, path[1:]), nil
+}
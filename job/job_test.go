@@ -0,0 +1,27 @@
+package job
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		{"one URL", Config{URLs: []string{"u"}}, true},
+		{"no URL", Config{}, false},
+		{"stdout", Config{URLs: []string{"u"}, Output: "-"}, true},
+		{"stdout in background", Config{URLs: []string{"u"}, Output: "-", Background: true}, false},
+		{"stdout at a time", Config{URLs: []string{"u"}, Output: "-", At: "2026-10-20T02:00"}, false},
+		{"file in background", Config{URLs: []string{"u"}, Output: "f", Background: true}, true},
+		{"shared file every hour", Config{URLs: []string{"u", "v"}, Output: "f", Every: "1h"}, false},
+		{"own file every hour", Config{URLs: []string{"u"}, Output: "f", Every: "1h"}, true},
+		{"follow without input file", Config{URLs: []string{"u"}, Follow: true}, false},
+		{"follow on a schedule", Config{InputFile: "i", Follow: true, Every: "1h"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelQuiet is above every level used by the program, so nothing is logged
+const LevelQuiet = slog.Level(12)
+
+// Options controls where log output goes and how much of it is written
+type Options struct {
+	// Level is the minimum level logged: slog.LevelDebug for -d, slog.LevelInfo
+	// by default and for -v, slog.LevelWarn for -nv and LevelQuiet for -q
+	Level slog.Level
+	// File is the log file for -o/-a; stderr is used when it is empty
+	File string
+	// Append keeps the existing contents of File (-a) instead of truncating it (-o)
+	Append bool
+	// Format is "text" (default) or "json"
+	Format string
+}
+
+// Level picks the log level from the verbosity flags, the most verbose one winning
+func Level(quiet, nonVerbose, verbose, debug bool) slog.Level {
+	switch {
+	case debug:
+		return slog.LevelDebug
+	case verbose:
+		return slog.LevelInfo
+	case nonVerbose:
+		return slog.LevelWarn
+	case quiet:
+		return LevelQuiet
+	}
+	return slog.LevelInfo
+}
+
+// New builds the logger described by opts. The returned function closes the
+// log file, if one was opened.
+func New(opts Options) (*slog.Logger, func() error, error) {
+	var w io.Writer = os.Stderr
+	closeFn := func() error { return nil }
+	timestamps := false
+
+	if opts.File != "" {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if opts.Append {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		file, err := os.OpenFile(opts.File, flags, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error opening log file: %v", err)
+		}
+		w = file
+		closeFn = file.Close
+		// Log files are read later, so each line carries its time
+		timestamps = true
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+	switch opts.Format {
+	case "", "text":
+		return slog.New(newTextHandler(w, handlerOpts, timestamps)), closeFn, nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), closeFn, nil
+	}
+	closeFn()
+	return nil, nil, fmt.Errorf("unknown log format %q (expected text or json)", opts.Format)
+}
+
+// textHandler writes one human-readable line per record: the message
+// followed by its attributes as key=value pairs. Warnings and errors are
+// prefixed with their level.
+type textHandler struct {
+	mu         *sync.Mutex
+	w          io.Writer
+	opts       *slog.HandlerOptions
+	timestamps bool
+	attrs      []slog.Attr
+	groups     []string
+}
+
+func newTextHandler(w io.Writer, opts *slog.HandlerOptions, timestamps bool) *textHandler {
+	return &textHandler{mu: &sync.Mutex{}, w: w, opts: opts, timestamps: timestamps}
+}
+
+// Enabled reports whether records at level are written
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+// Handle formats and writes a record
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if h.timestamps && !r.Time.IsZero() {
+		b.WriteString(r.Time.Format("2006/01/02 15:04:05 "))
+	}
+	if r.Level >= slog.LevelWarn {
+		b.WriteString(r.Level.String() + ": ")
+	}
+	b.WriteString(r.Message)
+
+	for _, a := range h.attrs {
+		writeAttr(&b, a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.qualify(a))
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// WithAttrs returns a handler that adds attrs to every record
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = append(h2.attrs, h.qualify(a))
+	}
+	return &h2
+}
+
+// WithGroup returns a handler that prefixes later attribute keys with name
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.groups = append(append([]string{}, h.groups...), name)
+	return &h2
+}
+
+// qualify prefixes the attribute key with the open groups
+func (h *textHandler) qualify(a slog.Attr) slog.Attr {
+	if len(h.groups) > 0 {
+		a.Key = strings.Join(h.groups, ".") + "." + a.Key
+	}
+	return a
+}
+
+// writeAttr appends " key=value", quoting values that contain spaces
+func writeAttr(b *strings.Builder, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	var value string
+	switch a.Value.Kind() {
+	case slog.KindTime:
+		value = a.Value.Time().Format(time.RFC3339)
+	case slog.KindDuration:
+		value = a.Value.Duration().Round(time.Millisecond).String()
+	default:
+		value = a.Value.String()
+	}
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	b.WriteString(" " + a.Key + "=" + value)
+}
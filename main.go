@@ -1,77 +1,551 @@
 package main
 
 import (
-	"flag"
+	"bufio"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 	"wget/bckgrdDownload"
+	"wget/config"
+	"wget/exitStatus"
 	"wget/fileDownload"
+	"wget/hooks"
+	"wget/httpClient"
 	"wget/inputDownload"
+	"wget/job"
+	"wget/logging"
 	"wget/mirrorDownload"
+	"wget/options"
 	"wget/rateDownload"
+	"wget/spiderCheck"
+
+	"golang.org/x/term"
 )
 
 func main() {
-	// Command-line flags
-	background := flag.Bool("B", false, "Run download in background and log output")
-	inputFile := flag.String("i", "", "Download multiple files from an input file")
-	rateLimit := flag.String("rate-limit", "", "Limit download speed (e.g., 300k, 700k, 2M)")
-	mirror := flag.Bool("mirror", false, "Mirror a website")
-	convertLinks := flag.Bool("convert-links", false, "Convert links for offline browsing")
-	reject := flag.String("reject", "", "Comma-separated list of file extensions to reject")
-	exclude := flag.String("X", "", "Comma-separated list of paths to exclude")
-	output := flag.String("O", "", "Save as different filename")
-	saveDir := flag.String("P", "", "Save file in specific directory")
-
-	flag.Parse()
-
-	// Ensure URL or input file is provided
-	if flag.NArg() == 0 && *inputFile == "" {
-		fmt.Println("Usage: wget [options] <URL>")
-		flag.Usage()
-		os.Exit(1)
-	}
-
-	url := flag.Arg(0)
-
-	// Convert reject and exclude flags to slices
-	var rejectExtensions, excludeDirs []string
+	// Command-line options
+	flags := options.NewParser("wget")
+	background := flags.Bool("background", "B", "Run download in background and log output")
+	inputFile := flags.String("input-file", "i", "", "Download multiple files from an input file")
+	follow := flags.Bool("follow", "", "Keep watching the input file and download URLs as they are appended")
+	rateLimit := flags.String("rate-limit", "", "", "Limit download speed (e.g., 300k, 700k, 2M)")
+	mirror := flags.Bool("mirror", "m", "Mirror a website")
+	spider := flags.Bool("spider", "", "Check that URLs exist without downloading them; with --mirror, report the broken links of a site")
+	convertLinks := flags.Bool("convert-links", "k", "Convert links for offline browsing")
+	reject := flags.String("reject", "R", "", "Comma-separated list of file extensions to reject")
+	exclude := flags.String("exclude-directories", "X", "", "Comma-separated list of paths to exclude")
+	output := flags.String("output-document", "O", "", "Save as different filename")
+	extract, extractDir := flags.Optional("extract", "Unpack tar, tar.gz, tar.xz, tar.zst and zip downloads, into VALUE if given (at most 100000 entries and 8 GiB each)")
+	pipeTo := flags.String("pipe-to", "", "", "Stream each download into this shell command instead of a file ({url} and {name} are filled in)")
+	saveDir := flags.String("directory-prefix", "P", "", "Save file in specific directory")
+	noClobber := flags.Bool("no-clobber", "nc", "Skip downloads that would overwrite existing files")
+	at := flags.String("at", "", "", "Start the background download at a given time (e.g., 2026-10-20T02:00)")
+	every := flags.String("every", "", "", "Repeat the background download on an interval (e.g., 6h) or cron expression")
+	execOnComplete := flags.String("exec-on-complete", "", "", "Run a command when a download completes (e.g., \"cmd {path} {url} {status}\")")
+	webhook := flags.String("webhook", "", "", "POST a JSON summary to this URL when a download completes")
+
+	// Request options
+	headers := flags.StringList("header", "", "Add a \"Name: value\" header to every request (repeatable)")
+	userAgent := flags.String("user-agent", "U", "", "Identify as this user agent instead of "+httpClient.DefaultUserAgent)
+	referer := flags.String("referer", "", "", "Send this Referer header")
+	acceptLanguage := flags.String("accept-language", "", "", "Send this Accept-Language header")
+	compression := flags.String("compression", "", "auto", "Compressed transfers: auto (gzip, deflate, br, zstd), gzip, or none to save encoded bytes as sent")
+	maxExpansion := flags.String("max-expansion-ratio", "", strconv.Itoa(httpClient.DefaultMaxExpansion), "Refuse compressed responses that expand more than this many times (0 for no limit)")
+	method := flags.String("method", "", "", "Use this HTTP method instead of GET (e.g., POST, PUT, DELETE)")
+	postData := flags.String("post-data", "", "", "POST this string as the request body")
+	postFile := flags.String("post-file", "", "", "POST the contents of this file as the request body")
+	bodyData := flags.String("body-data", "", "", "Send this string as the body of the --method request")
+	bodyFile := flags.String("body-file", "", "", "Send the contents of this file as the body of the --method request")
+	loadCookies := flags.String("load-cookies", "", "", "Load cookies from a cookies.txt file before the first download")
+	saveCookies := flags.String("save-cookies", "", "", "Save cookies to a cookies.txt file after the run")
+	keepSessionCookies := flags.Bool("keep-session-cookies", "", "Save session cookies too (with --save-cookies)")
+	noCookies := flags.Bool("no-cookies", "", "Don't send or store cookies")
+
+	// Timeouts and retries
+	timeout := flags.String("timeout", "T", "", "Set the DNS, connect and read timeouts at once (seconds or e.g. 30s, 2m)")
+	dnsTimeout := flags.String("dns-timeout", "", "", "Give up on a DNS lookup after this long")
+	connectTimeout := flags.String("connect-timeout", "", "", "Give up connecting after this long")
+	readTimeout := flags.String("read-timeout", "", "", "Give up when no data arrives for this long (default 900s)")
+	deadline := flags.String("deadline", "", "", "Time budget of each file, retries included")
+	tries := flags.String("tries", "t", "20", "Attempts per file for timeouts and dropped connections (0 or inf for no limit)")
+	waitRetry := flags.String("waitretry", "", "10", "Wait up to this long between attempts (1s more after each failure)")
+
+	// Addresses and name resolution
+	inet4Only := flags.Bool("inet4-only", "4", "Connect to IPv4 addresses only")
+	inet6Only := flags.Bool("inet6-only", "6", "Connect to IPv6 addresses only")
+	preferFamily := flags.String("prefer-family", "", "", "Try addresses of this family first: IPv4, IPv6 or none")
+	resolve := flags.StringList("resolve", "", "Use this address for a host and port, e.g. example.com:443:10.0.0.5 (repeatable)")
+	bindAddress := flags.String("bind-address", "", "", "Connect from this local address")
+	unixSocket := flags.String("unix-socket", "", "", "Connect to this Unix domain socket instead of the host in the URL")
+	dnsServers := flags.String("dns-servers", "", "", "Comma-separated DNS servers to use instead of the system ones")
+
+	// Proxy options
+	proxy := flags.String("proxy", "", "", "Use this proxy instead of http_proxy/https_proxy (e.g., proxy:3128, socks5://host:1080)")
+	proxyUser := flags.String("proxy-user", "", "", "User name for proxy authentication")
+	proxyPassword := flags.String("proxy-password", "", "", "Password for proxy authentication")
+	proxyPAC := flags.String("proxy-pac", "", "", "Choose proxies with this proxy auto-config (PAC) file or URL")
+	noProxy := flags.Bool("no-proxy", "", "Don't use a proxy, even if one is set in the environment")
+
+	// TLS options
+	caCertificate := flags.String("ca-certificate", "", "", "Trust the CAs in this PEM file as well as the system ones")
+	caDirectory := flags.String("ca-directory", "", "", "Trust the CAs in the PEM files of this directory")
+	certificate := flags.String("certificate", "", "", "Client certificate (PEM) for servers that require one")
+	privateKey := flags.String("private-key", "", "", "Private key (PEM) for --certificate, if not in the same file")
+	noCheckCertificate := flags.Bool("no-check-certificate", "", "Don't verify the server certificate (insecure)")
+	secureProtocol := flags.String("secure-protocol", "", "auto", "Minimum TLS version: auto, TLSv1_2 or TLSv1_3")
+	hstsFile := flags.String("hsts-file", "", "", "Use this HSTS database instead of ~/.wget-hsts")
+	noHSTS := flags.Bool("no-hsts", "", "Don't upgrade to https for hosts with an HSTS policy, and don't record policies")
+	pinnedPubKey := flags.String("pinnedpubkey", "", "", "Require the server key to match sha256//<base64> hashes (';'-separated) or a key file")
+
+	// Authentication options
+	user := flags.String("user", "", "", "User name for HTTP authentication")
+	password := flags.String("password", "", "", "Password for HTTP authentication")
+	httpUser := flags.String("http-user", "", "", "User name for HTTP authentication (overrides --user)")
+	httpPassword := flags.String("http-password", "", "", "Password for HTTP authentication (overrides --password)")
+	askPassword := flags.Bool("ask-password", "", "Prompt for the password instead of taking it from the command line")
+	authNoChallenge := flags.Bool("auth-no-challenge", "", "Send Basic credentials without waiting for the server to ask")
+	bearerToken := flags.String("bearer-token", "", "", "Send this OAuth2 bearer token with every request")
+	oauthTokenURL := flags.String("oauth-token-url", "", "", "Fetch bearer tokens from this OAuth2 token endpoint (client credentials grant)")
+	clientID := flags.String("client-id", "", "", "OAuth2 client ID for --oauth-token-url")
+	clientSecret := flags.String("client-secret", "", "", "OAuth2 client secret for --oauth-token-url")
+
+	// Logging options
+	quiet := flags.Bool("quiet", "q", "Quiet mode, no output")
+	nonVerbose := flags.Bool("no-verbose", "nv", "Non-verbose, only warnings and errors")
+	verbose := flags.Bool("verbose", "v", "Verbose output (default)")
+	debug := flags.Bool("debug", "d", "Print debug output")
+	logFile := flags.String("output-file", "o", "", "Write log messages to a file")
+	appendFile := flags.String("append-output", "a", "", "Append log messages to a file")
+	logFormat := flags.String("log-format", "", "text", "Log format: text or json")
+	help := flags.Bool("help", "h", "Print this help")
+
+	// Configuration
+	configFile := flags.String("config", "", "", "Read settings from this wgetrc file after /etc/wgetrc and ~/.wgetrc")
+	flags.Func("execute", "e", "Run a wgetrc-style command, e.g. -e \"limit_rate = 300k\"", flags.Execute)
+
+	// GNU wget spellings, including the wgetrc names that differ from the option names
+	flags.Alias("limit-rate", "rate-limit")
+	flags.Alias("dir-prefix", "directory-prefix")
+	flags.Alias("input", "input-file")
+	flags.Alias("logfile", "output-file")
+
+	urls, err := flags.Parse(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wget:", err)
+		fmt.Fprintln(os.Stderr, "Try 'wget --help' for more options.")
+		os.Exit(exitStatus.Parse)
+	}
+	if *help {
+		flags.Usage(os.Stdout)
+		return
+	}
+
+	// Settings from wgetrc files apply beneath the command line
+	var configWarnings []string
+	for _, file := range config.Files(*configFile) {
+		warnings, err := config.Load(file, flags.SetDefault)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "wget:", err)
+			os.Exit(exitStatus.Parse)
+		}
+		configWarnings = append(configWarnings, warnings...)
+	}
+
+	// Turn the options into one job configuration that every mode honors
+	cfg := &job.Config{
+		URLs:       urls,
+		InputFile:  *inputFile,
+		Follow:     *follow,
+		Background: *background,
+		At:         *at,
+		Every:      *every,
+		Output:     *output,
+		PipeTo:     *pipeTo,
+		Extract:    *extract,
+		ExtractDir: *extractDir,
+		SaveDir:    *saveDir,
+		NoClobber:  *noClobber,
+		Mirror:     *mirror,
+		Spider:     *spider,
+
+		ConvertLinks: *convertLinks,
+		Hooks:        hooks.Hooks{Exec: *execOnComplete, Webhook: *webhook},
+	}
+	// Every mode saves below the same directories, so "~" is expanded once
+	for _, dir := range []*string{&cfg.SaveDir, &cfg.ExtractDir} {
+		if *dir, err = job.ExpandHome(*dir); err != nil {
+			fmt.Fprintln(os.Stderr, "wget:", err)
+			os.Exit(exitStatus.FileIO)
+		}
+	}
+	if *inet4Only && *inet6Only {
+		fmt.Fprintln(os.Stderr, "wget: -4 and -6 can't be used together")
+		os.Exit(exitStatus.Parse)
+	}
+	family := ""
+	switch {
+	case *inet4Only:
+		family = "4"
+	case *inet6Only:
+		family = "6"
+	}
+	timeouts := struct{ dns, connect, read time.Duration }{read: 900 * time.Second}
+	if *rateLimit != "" {
+		rate, err := rateDownload.ParseRateLimit(*rateLimit)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "wget:", err)
+			os.Exit(exitStatus.Parse)
+		}
+		cfg.RateLimit = rate
+	}
+	if err := cfg.SetRequest(*method, *postData, *postFile, *bodyData, *bodyFile); err != nil {
+		fmt.Fprintln(os.Stderr, "wget:", err)
+		os.Exit(exitStatus.Parse)
+	}
+	expansion, err := strconv.ParseInt(*maxExpansion, 10, 64)
+	if err != nil || expansion < 0 {
+		fmt.Fprintf(os.Stderr, "wget: invalid --max-expansion-ratio %q\n", *maxExpansion)
+		os.Exit(exitStatus.Parse)
+	}
+	if cfg.Tries, err = parseTries(*tries); err != nil {
+		fmt.Fprintln(os.Stderr, "wget:", err)
+		os.Exit(exitStatus.Parse)
+	}
+	// A general --timeout applies to whichever specific timeout isn't given
+	durations := []struct {
+		value  string
+		target *time.Duration
+	}{
+		{*timeout, &timeouts.dns}, {*timeout, &timeouts.connect}, {*timeout, &timeouts.read},
+		{*dnsTimeout, &timeouts.dns}, {*connectTimeout, &timeouts.connect}, {*readTimeout, &timeouts.read},
+		{*deadline, &cfg.Deadline}, {*waitRetry, &cfg.WaitRetry},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		if *d.target, err = parseSeconds(d.value); err != nil {
+			fmt.Fprintln(os.Stderr, "wget:", err)
+			os.Exit(exitStatus.Parse)
+		}
+	}
 	if *reject != "" {
-		rejectExtensions = strings.Split(*reject, ",")
+		cfg.Reject = strings.Split(*reject, ",")
 	}
 	if *exclude != "" {
-		excludeDirs = strings.Split(*exclude, ",")
+		cfg.Exclude = strings.Split(*exclude, ",")
+	}
+	// HTTP-specific credentials win over the generic ones
+	if *httpUser != "" {
+		*user = *httpUser
+	}
+	if *httpPassword != "" {
+		*password = *httpPassword
+	}
+	if *askPassword {
+		if *password != "" {
+			fmt.Fprintln(os.Stderr, "wget: --ask-password can't be used together with --password")
+			os.Exit(exitStatus.Parse)
+		}
+		// A background copy reads the password typed before it was started
+		// from the pipe on its standard input
+		if *password, err = readPassword(*user); err != nil {
+			fmt.Fprintln(os.Stderr, "wget:", err)
+			os.Exit(exitStatus.Generic)
+		}
 	}
 
-	// Background download
-	if *background {
-		log.Println("Starting background download...")
-		bckgrdDownload.Start(url)
-		return
+	client, err := httpClient.New(httpClient.Options{
+		Headers:         *headers,
+		UserAgent:       *userAgent,
+		Referer:         *referer,
+		AcceptLanguage:  *acceptLanguage,
+		User:            *user,
+		Password:        *password,
+		AuthNoChallenge: *authNoChallenge,
+		BearerToken:     *bearerToken,
+		OAuthTokenURL:   *oauthTokenURL,
+		ClientID:        *clientID,
+		ClientSecret:    *clientSecret,
+
+		LoadCookies:        *loadCookies,
+		SaveCookies:        *saveCookies,
+		KeepSessionCookies: *keepSessionCookies,
+		NoCookies:          *noCookies,
+
+		Proxy:         *proxy,
+		ProxyUser:     *proxyUser,
+		ProxyPassword: *proxyPassword,
+		ProxyPAC:      *proxyPAC,
+		NoProxy:       *noProxy,
+
+		CACertificate:      *caCertificate,
+		CADirectory:        *caDirectory,
+		Certificate:        *certificate,
+		PrivateKey:         *privateKey,
+		NoCheckCertificate: *noCheckCertificate,
+		SecureProtocol:     *secureProtocol,
+		PinnedPubKey:       *pinnedPubKey,
+		HSTSFile:           *hstsFile,
+		NoHSTS:             *noHSTS,
+
+		DNSTimeout:     timeouts.dns,
+		ConnectTimeout: timeouts.connect,
+		ReadTimeout:    timeouts.read,
+
+		Family:       family,
+		PreferFamily: *preferFamily,
+		Resolve:      *resolve,
+		BindAddress:  *bindAddress,
+		DNSServers:   *dnsServers,
+		UnixSocket:   *unixSocket,
+
+		Compression:  *compression,
+		MaxExpansion: expansion,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wget:", err)
+		// Option mistakes are parse errors; an unreadable cookie file keeps its own status
+		code := exitStatus.Of(err)
+		if code == exitStatus.Generic {
+			code = exitStatus.Parse
+		}
+		os.Exit(code)
+	}
+	cfg.Client = client
+	cfg.Hooks.Transport = client.Transport()
+	// Schedules are checked here too, before the run detaches
+	err = cfg.Validate()
+	if err == nil {
+		err = bckgrdDownload.Check(cfg)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wget:", err)
+		fmt.Fprintln(os.Stderr, "Try 'wget --help' for more options.")
+		os.Exit(exitStatus.Parse)
+	}
+
+	// Set up logging. Background and scheduled runs write to wget-log unless told otherwise.
+	logOpts := logging.Options{
+		Level:  logging.Level(*quiet, *nonVerbose, *verbose, *debug),
+		Format: *logFormat,
+	}
+	switch {
+	case *appendFile != "":
+		logOpts.File, logOpts.Append = *appendFile, true
+	case *logFile != "":
+		logOpts.File = *logFile
+	}
+	if cfg.Background || cfg.At != "" || cfg.Every != "" {
+		var input []byte
+		if *askPassword {
+			input = []byte(*password + "\n")
+		}
+		detached, err := bckgrdDownload.Start(&logOpts, input)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "wget:", err)
+			os.Exit(exitStatus.Generic)
+		}
+		if detached {
+			// The background copy does the work and saves cookies and HSTS
+			os.Exit(exitStatus.Success)
+		}
+	}
+	logger, closeLog, err := logging.New(logOpts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitStatus.Generic)
+	}
+	cfg.Logger = logger
+	for _, warning := range configWarnings {
+		logger.Warn("Config: " + warning)
+	}
+	cfg.ShowProgress = logOpts.File == "" && logOpts.Level <= slog.LevelInfo
+
+	// -O - and -O with several URLs write one document for the whole run
+	closeDocument, err := openDocument(cfg)
+	if err != nil {
+		logger.Error("Cannot open output document", "err", err)
+		closeLog()
+		os.Exit(exitStatus.FileIO)
+	}
+
+	// Scheduled or recurring downloads repeat the whole run
+	if cfg.At != "" || cfg.Every != "" {
+		logger.Info("Scheduling background download")
+		err = bckgrdDownload.Schedule(cfg, runAll)
+		if err != nil {
+			logger.Error("Schedule failed", "err", err)
+		}
+	} else {
+		err = runAll(cfg)
+	}
+
+	// Cookies and HSTS policies collected by every download of the run
+	if saveErr := client.Save(); saveErr != nil {
+		logger.Error("Saving failed", "err", saveErr)
+		err = exitStatus.Wrap(exitStatus.Combine(exitStatus.Of(err), exitStatus.Of(saveErr)), errors.Join(err, saveErr))
+	}
+
+	if closeErr := closeDocument(); closeErr != nil {
+		logger.Error("Closing output document failed", "err", closeErr)
+		err = exitStatus.Wrap(exitStatus.Combine(exitStatus.Of(err), exitStatus.FileIO), errors.Join(err, closeErr))
+	}
+
+	// Exit with wget's status code so scripts can branch on the result
+	code := exitStatus.Of(err)
+	if code != exitStatus.Success {
+		logger.Debug("Run finished with errors", "exit_status", code, "err", err)
+	}
+	closeLog()
+	os.Exit(code)
+}
+
+// openDocument sets cfg.Document when the run writes a single document.
+// A file is truncated once here so every download is appended to it, as
+// in GNU wget. The returned function closes it at the end of the run.
+func openDocument(cfg *job.Config) (func() error, error) {
+	switch {
+	case !cfg.SharesDocument():
+		return func() error { return nil }, nil
+	case cfg.Output == "-":
+		// Keep stdout for the data; hook commands print to stderr instead
+		cfg.Document = os.Stdout
+		cfg.Hooks.Stdout = os.Stderr
+		return func() error { return nil }, nil
+	}
+
+	output := cfg.Output
+	if cfg.SaveDir != "" {
+		if err := os.MkdirAll(cfg.SaveDir, os.ModePerm); err != nil {
+			return nil, err
+		}
+		output = filepath.Join(cfg.SaveDir, output)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Document = file
+	return file.Close, nil
+}
+
+// runAll downloads every URL from the command line and then the input file,
+// as GNU wget does. The returned error carries the combined exit status of
+// every failure.
+func runAll(cfg *job.Config) error {
+	status, failed := exitStatus.Success, 0
+
+	for _, url := range cfg.URLs {
+		if err := runURL(cfg, url); err != nil {
+			status = exitStatus.Combine(status, exitStatus.Of(err))
+			failed++
+		}
 	}
 
 	// Download multiple files from input list
-	if *inputFile != "" {
-		inputDownload.Start(*inputFile)
-		return
+	if cfg.InputFile != "" && !cfg.Follow {
+		if err := inputDownload.Start(cfg, runURL); err != nil {
+			cfg.Logger.Error("Batch download failed", "err", err)
+			status = exitStatus.Combine(status, exitStatus.Of(err))
+			failed++
+		}
 	}
 
-	// Rate-limited download
-	if *rateLimit != "" {
-		rateDownload.Start(url, *rateLimit)
-		return
+	if cfg.Follow {
+		if err := inputDownload.Follow(cfg, runURL); err != nil {
+			status = exitStatus.Combine(status, exitStatus.Of(err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return exitStatus.Wrap(status, fmt.Errorf("%d downloads failed", failed))
 	}
+	return nil
+}
 
-	// Mirror a website
-	if *mirror {
-		mirrorDownload.Start(url, *convertLinks, rejectExtensions, excludeDirs)
-		return
+// runURL mirrors or downloads a single URL and fires the completion hooks
+func runURL(cfg *job.Config, url string) error {
+	startTime := time.Now()
+	cfg.Client.AddStartURL(url)
+
+	var path string
+	var err error
+	switch {
+	case cfg.Spider && cfg.Mirror:
+		// Check every link of a website
+		path, err = spiderCheck.Crawl(url, cfg)
+	case cfg.Spider:
+		// Check that the file exists
+		path, err = spiderCheck.Start(url, cfg)
+	case cfg.Mirror:
+		// Mirror a website
+		path, err = mirrorDownload.Start(url, cfg)
+	default:
+		// Normal file download
+		path, err = fileDownload.Start(url, cfg)
+	}
+
+	if cfg.Hooks.Enabled() {
+		result := hooks.NewResult(cfg.Mode(), url, path, startTime, err)
+		var pipeErr *fileDownload.PipeError
+		if errors.As(err, &pipeErr) {
+			result.PipeExitStatus = &pipeErr.ExitCode
+		} else if err == nil && cfg.PipeTo != "" && !cfg.Mirror {
+			result.PipeExitStatus = new(int)
+		}
+		cfg.Hooks.Fire(result, cfg.Logger)
+	}
+	return err
+}
+
+// parseSeconds reads a time like wget, as a number of seconds, or as a Go
+// duration such as 1m30s. 0 turns the limit off.
+func parseSeconds(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid time %q (expected seconds or e.g. 30s, 2m)", value)
 	}
+	return d, nil
+}
 
-	// Normal file download
-	fileDownload.Start(url, *output, *saveDir)
+// parseTries reads -t/--tries; 0 and inf mean no limit
+func parseTries(value string) (int, error) {
+	if value == "inf" {
+		return 0, nil
+	}
+	tries, err := strconv.Atoi(value)
+	if err != nil || tries < 0 {
+		return 0, fmt.Errorf("invalid number of tries %q", value)
+	}
+	return tries, nil
+}
+
+// readPassword prompts on the terminal for the password of user without echoing it
+func readPassword(user string) (string, error) {
+	fmt.Fprintf(os.Stderr, "Password for user '%s': ", user)
+	defer fmt.Fprintln(os.Stderr)
 
-	//strings.Join(rejectExtensions, ","), strings.Join(excludeDirs, ",")
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		// Piped input: read a single line
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("cannot read password: %v", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	if err != nil {
+		return "", fmt.Errorf("cannot read password: %v", err)
+	}
+	return string(pass), nil
 }
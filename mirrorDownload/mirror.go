@@ -1,67 +1,92 @@
 package mirrorDownload
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"time"
 	"wget/downloader" // Handles downloading resources
+	"wget/exitStatus"
+	"wget/job"
 )
 
-// Start begins mirroring a website
-func Start(siteURL string, convertLinks bool, rejectExtensions []string, excludeDirs []string) {
+// Start begins mirroring a website and returns the directory it was saved to.
+// The site is saved under cfg.SaveDir, or mirrored_sites when it is empty.
+func Start(siteURL string, cfg *job.Config) (string, error) {
+	logger := cfg.Logger
 	startTime := time.Now()
-	fmt.Printf("Start time: %s\n", startTime.Format("2006-01-02 15:04:05"))
+	logger.Info("Mirror started", "time", startTime.Format("2006-01-02 15:04:05"))
 
 	parsedURL, err := url.Parse(siteURL)
 	if err != nil {
-		fmt.Println("Invalid URL:", err)
-		return
+		logger.Error("Invalid URL", "url", siteURL, "err", err)
+		return "", err
 	}
 
 	// Define save directory for the mirrored site
 	domain := parsedURL.Hostname()
-	saveDir := filepath.Join("mirrored_sites", domain)
+	root := cfg.SaveDir
+	if root == "" {
+		root = "mirrored_sites"
+	}
+	saveDir := filepath.Join(root, domain)
 	err = os.MkdirAll(saveDir, 0755)
 	if err != nil {
-		fmt.Println("Error creating directory:", err)
-		return
+		logger.Error("Error creating directory", "dir", saveDir, "err", err)
+		return "", err
 	}
 
-	fmt.Println("Mirroring:", siteURL)
+	logger.Info("Mirroring", "url", siteURL)
 
 	// Fetch the HTML content
-	resp, err := http.Get(siteURL)
+	var htmlContent []byte
+	err = cfg.Retry(siteURL, func(ctx context.Context) error {
+		var err error
+		htmlContent, err = fetchPage(ctx, siteURL, cfg)
+		return err
+	})
 	if err != nil {
-		fmt.Println("Error fetching site:", err)
-		return
+		logger.Error("Error fetching site", "url", siteURL, "err", err)
+		return saveDir, err
 	}
-	defer resp.Body.Close()
 
-	htmlContent, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading HTML content:", err)
-		return
+	// Download resources (CSS, images, JS, etc.). Failed resources don't stop
+	// the mirror, but their exit code is reported once it finishes.
+	resourceErr := downloader.DownloadResources(string(htmlContent), siteURL, saveDir, cfg)
+	if resourceErr != nil {
+		logger.Error("Error downloading resources", "err", resourceErr)
 	}
 
-	// Download resources (CSS, images, JS, etc.)
-	err = downloader.DownloadResources(string(htmlContent), siteURL, saveDir, excludeDirs)
+	// Fix file paths inside HTML and CSS files
+	err = ProcessDownloadedFiles(saveDir, logger)
 	if err != nil {
-		fmt.Println("Error downloading resources:", err)
-		return
+		logger.Error("Error updating file paths", "err", err)
 	}
 
-	// Fix file paths inside HTML and CSS files
-	ProcessDownloadedFiles(saveDir)
+	endTime := time.Now()
+	logger.Info("Mirror finished", "time", endTime.Format("2006-01-02 15:04:05"))
+	logger.Info("Time taken", "seconds", fmt.Sprintf("%.2f", endTime.Sub(startTime).Seconds()))
+	if resourceErr != nil {
+		logger.Warn("Website mirrored with errors", "dir", saveDir)
+		return saveDir, resourceErr
+	}
+	logger.Info("Website successfully mirrored", "dir", saveDir)
+	return saveDir, nil
+}
+
+// fetchPage downloads the HTML of a page
+func fetchPage(ctx context.Context, pageURL string, cfg *job.Config) ([]byte, error) {
+	resp, err := cfg.Client.GetContext(ctx, pageURL)
 	if err != nil {
-		fmt.Println("Error updating file paths:", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	endTime := time.Now()
-	fmt.Printf("End time: %s\n", endTime.Format("2006-01-02 15:04:05"))
-	fmt.Printf("Time taken: %.2f seconds\n", endTime.Sub(startTime).Seconds())
-	fmt.Println("Website successfully mirrored to:", saveDir)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, exitStatus.Wrap(exitStatus.ForHTTPStatus(resp.StatusCode), fmt.Errorf("server responded with %s", resp.Status))
+	}
+	return io.ReadAll(resp.Body)
 }
@@ -3,6 +3,7 @@ package mirrorDownload
 import (
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -10,7 +11,7 @@ import (
 )
 
 // fixFilePaths updates file paths in HTML and CSS files
-func fixFilePaths(filePath string) error {
+func fixFilePaths(filePath string, logger *slog.Logger) error {
 	// Read the file content
 	content, err := ioutil.ReadFile(filePath)
 	if err != nil {
@@ -42,18 +43,18 @@ func fixFilePaths(filePath string) error {
 		return fmt.Errorf("failed to write file %s: %v", filePath, err)
 	}
 
-	fmt.Printf("Updated file paths in %s\n", filePath)
+	logger.Info("Updated file paths", "path", filePath)
 	return nil
 }
 
 // ProcessDownloadedFiles walks through the download directory and fixes file paths
-func ProcessDownloadedFiles(rootDir string) error {
+func ProcessDownloadedFiles(rootDir string, logger *slog.Logger) error {
 	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if !info.IsDir() && (strings.HasSuffix(path, ".html") || strings.HasSuffix(path, ".css")) {
-			return fixFilePaths(path)
+			return fixFilePaths(path, logger)
 		}
 		return nil
 	})
@@ -0,0 +1,433 @@
+package options
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// option is a single command-line option
+type option struct {
+	long   string
+	short  string
+	usage  string
+	hasArg bool
+	isBool bool
+	// optional options take "--name=value" but may be given bare
+	optional bool
+	set      func(string) error
+	// setDefault stores a value from a configuration file when that is
+	// kept apart from the command line, as for repeatable options
+	setDefault func(string) error
+	// explicit is set once the option was given on the command line, so
+	// configuration files don't override it
+	explicit bool
+	// negates points at the switch a "--no-" option turns off
+	negates *option
+}
+
+// Parser reads command lines the way GNU wget does: long options with
+// "--name value" or "--name=value", short options that can be bundled
+// ("-qO-"), wget's two-letter "-n" options ("-nv", "-nc"), unambiguous
+// abbreviations of long options and options in any position. Everything
+// after "--" is positional.
+type Parser struct {
+	name    string
+	options []*option
+	long    map[string]*option
+	short   map[string]*option
+	// negated holds the "--no-" forms of boolean options
+	negated map[string]*option
+}
+
+// NewParser returns an empty parser for the named program
+func NewParser(name string) *Parser {
+	return &Parser{
+		name:    name,
+		long:    make(map[string]*option),
+		short:   make(map[string]*option),
+		negated: make(map[string]*option),
+	}
+}
+
+// Bool defines a switch. short may be empty, a single letter or a two-letter
+// "n" option such as "nv". Boolean long options can be negated with "--no-".
+func (p *Parser) Bool(long, short string, usage string) *bool {
+	value := new(bool)
+	opt := &option{long: long, short: short, usage: usage, isBool: true, set: func(v string) error {
+		if v == "" {
+			*value = true
+			return nil
+		}
+		b, err := parseBool(v)
+		if err != nil {
+			return err
+		}
+		*value = b
+		return nil
+	}}
+	p.add(opt)
+	p.negated["no-"+long] = &option{long: "no-" + long, negates: opt, set: func(string) error {
+		*value = false
+		return nil
+	}}
+	return value
+}
+
+// StringList defines a repeatable option whose values are collected in
+// order, those from configuration files before those from the command
+// line. An empty value clears the values given so far from the same
+// source, so a wgetrc file can't drop values from the command line.
+func (p *Parser) StringList(long, short, usage string) *[]string {
+	values := new([]string)
+	var fromConfig, fromCommandLine []string
+	collect := func(list *[]string) func(string) error {
+		return func(v string) error {
+			if v == "" {
+				*list = nil
+			} else {
+				*list = append(*list, v)
+			}
+			*values = append(append([]string(nil), fromConfig...), fromCommandLine...)
+			return nil
+		}
+	}
+	p.add(&option{long: long, short: short, usage: usage, hasArg: true,
+		set: collect(&fromCommandLine), setDefault: collect(&fromConfig)})
+	return values
+}
+
+// Func defines an option that takes a value and passes it to fn
+func (p *Parser) Func(long, short, usage string, fn func(string) error) {
+	p.add(&option{long: long, short: short, usage: usage, hasArg: true, set: fn})
+}
+
+// String defines an option that takes a value
+func (p *Parser) String(long, short, value, usage string) *string {
+	s := &value
+	p.StringVar(s, long, short, usage)
+	return s
+}
+
+// StringVar defines an option that stores its value in s
+func (p *Parser) StringVar(s *string, long, short, usage string) {
+	p.add(&option{long: long, short: short, usage: usage, hasArg: true, set: func(v string) error {
+		*s = v
+		return nil
+	}})
+}
+
+// Optional defines a long option whose value may be left out, as in
+// "--extract" and "--extract=DIR". given reports whether it was used.
+func (p *Parser) Optional(long, usage string) (given *bool, value *string) {
+	given, value = new(bool), new(string)
+	p.add(&option{long: long, usage: usage, hasArg: true, optional: true, set: func(v string) error {
+		*given, *value = true, v
+		return nil
+	}})
+	return given, value
+}
+
+// Alias makes alias another long name for the existing option long, e.g.
+// "limit-rate" for "rate-limit"
+func (p *Parser) Alias(alias, long string) {
+	opt, ok := p.long[long]
+	if !ok {
+		panic("options: alias for unknown option " + long)
+	}
+	p.long[alias] = opt
+}
+
+// add registers opt under its long and short names
+func (p *Parser) add(opt *option) {
+	if _, ok := p.long[opt.long]; ok {
+		panic("options: duplicate option " + opt.long)
+	}
+	p.options = append(p.options, opt)
+	p.long[opt.long] = opt
+	if opt.short != "" {
+		p.short[opt.short] = opt
+	}
+}
+
+// Parse processes args (without the program name) and returns the
+// positional arguments in order
+func (p *Parser) Parse(args []string) ([]string, error) {
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			return append(positional, args[i+1:]...), nil
+
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			opt, err := p.findLong(name)
+			if err != nil {
+				return nil, err
+			}
+			if !opt.hasArg {
+				if hasValue {
+					return nil, fmt.Errorf("option '--%s' doesn't allow an argument", name)
+				}
+				if err := p.setExplicit(opt, ""); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if !hasValue && !opt.optional {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("option '--%s' requires an argument", name)
+				}
+				i++
+				value = args[i]
+			}
+			if err := p.setExplicit(opt, value); err != nil {
+				return nil, fmt.Errorf("--%s: %v", name, err)
+			}
+
+		case len(arg) > 1 && arg[0] == '-':
+			consumed, err := p.parseShort(arg[1:], args[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			i += consumed
+
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	return positional, nil
+}
+
+// findLong returns the long option called name. Like getopt_long, it also
+// accepts any prefix that belongs to only one option, e.g. "--no-check"
+// for "--no-check-certificate".
+func (p *Parser) findLong(name string) (*option, error) {
+	if opt, ok := p.long[name]; ok {
+		return opt, nil
+	}
+	if opt, ok := p.negated[name]; ok {
+		return opt, nil
+	}
+
+	var match *option
+	var candidates []string
+	ambiguous := false
+	for _, names := range []map[string]*option{p.long, p.negated} {
+		for long, opt := range names {
+			if name == "" || !strings.HasPrefix(long, name) {
+				continue
+			}
+			if shadow, ok := p.long[long]; ok && shadow != opt {
+				// A real option called "no-..." wins over a negation
+				continue
+			}
+			candidates = append(candidates, "'--"+long+"'")
+			// Aliases of the same option aren't ambiguous
+			if match != nil && match != opt {
+				ambiguous = true
+			}
+			match = opt
+		}
+	}
+	switch {
+	case ambiguous:
+		sort.Strings(candidates)
+		candidates = slices.Compact(candidates)
+		return nil, fmt.Errorf("option '--%s' is ambiguous; possibilities: %s", name, strings.Join(candidates, " "))
+	case match == nil:
+		return nil, fmt.Errorf("unrecognized option '--%s'", name)
+	}
+	return match, nil
+}
+
+// parseShort handles a cluster of short options such as "qO-" and returns
+// how many of the following arguments were used as values
+func (p *Parser) parseShort(cluster string, rest []string) (int, error) {
+	for len(cluster) > 0 {
+		// Two-letter options like "nv" take priority over bundled letters
+		name := cluster[:1]
+		if len(cluster) >= 2 {
+			if _, ok := p.short[cluster[:2]]; ok {
+				name = cluster[:2]
+			}
+		}
+		opt, ok := p.short[name]
+		if !ok {
+			return 0, fmt.Errorf("invalid option -- '%s'", name)
+		}
+		cluster = cluster[len(name):]
+
+		if !opt.hasArg {
+			if err := p.setExplicit(opt, ""); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		// The rest of the cluster is the value ("-O-", "-Ofile"); "-O=file"
+		// is accepted too for compatibility with earlier versions
+		if cluster != "" {
+			return 0, p.setShort(opt, name, strings.TrimPrefix(cluster, "="))
+		}
+		if len(rest) == 0 {
+			return 0, fmt.Errorf("option requires an argument -- '%s'", name)
+		}
+		return 1, p.setShort(opt, name, rest[0])
+	}
+	return 0, nil
+}
+
+// setShort stores the value of a short option
+func (p *Parser) setShort(opt *option, name, value string) error {
+	if err := p.setExplicit(opt, value); err != nil {
+		return fmt.Errorf("-%s: %v", name, err)
+	}
+	return nil
+}
+
+// setExplicit stores a value given on the command line
+func (p *Parser) setExplicit(opt *option, value string) error {
+	opt.explicit = true
+	if opt.negates != nil {
+		opt.negates.explicit = true
+	}
+	return opt.set(value)
+}
+
+// Execute runs a wgetrc-style command such as "limit_rate = 300k" given on
+// the command line with -e. Like any other command-line option it takes
+// precedence over configuration files.
+func (p *Parser) Execute(command string) error {
+	key, value, err := ParseCommand(command)
+	if err != nil {
+		return err
+	}
+	opt, err := p.lookup(key)
+	if err != nil {
+		return err
+	}
+	opt.explicit = true
+	if opt.negates != nil {
+		opt.negates.explicit = true
+	}
+	return assign(opt, value)
+}
+
+// SetDefault applies a setting from a configuration file. Keys are matched
+// against the long option names ignoring case, '-' and '_', so "limit_rate",
+// "limitrate" and "limit-rate" are the same. Options already given on the
+// command line keep their value, except repeatable ones, which collect
+// values from both.
+func (p *Parser) SetDefault(key, value string) error {
+	opt, err := p.lookup(key)
+	if err != nil {
+		return err
+	}
+	if opt.setDefault != nil {
+		return opt.setDefault(value)
+	}
+	if opt.explicit || (opt.negates != nil && opt.negates.explicit) {
+		return nil
+	}
+	return assign(opt, value)
+}
+
+// assign sets opt from a wgetrc value. Switches need a real boolean value
+// there, and "no_" keys store its opposite.
+func assign(opt *option, value string) error {
+	if !opt.isBool && opt.negates == nil {
+		return opt.set(value)
+	}
+	b, err := parseBool(value)
+	if err != nil {
+		return err
+	}
+	if opt.negates != nil {
+		opt, b = opt.negates, !b
+	}
+	return opt.set(strconv.FormatBool(b))
+}
+
+// lookup finds the option for a wgetrc key
+func (p *Parser) lookup(key string) (*option, error) {
+	want := NormalizeKey(key)
+	for name, opt := range p.long {
+		if NormalizeKey(name) == want {
+			return opt, nil
+		}
+	}
+	for name, opt := range p.negated {
+		if NormalizeKey(name) == want {
+			return opt, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown command '%s'", key)
+}
+
+// ParseCommand splits a wgetrc line of the form "key = value"
+func ParseCommand(command string) (key, value string, err error) {
+	key, value, found := strings.Cut(command, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" {
+		return "", "", fmt.Errorf("invalid command '%s' (expected key = value)", strings.TrimSpace(command))
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+// NormalizeKey drops case, '-' and '_' from an option or wgetrc key, so
+// "limit_rate" and "limit-rate" compare equal
+func NormalizeKey(key string) string {
+	key = strings.ToLower(key)
+	key = strings.ReplaceAll(key, "-", "")
+	return strings.ReplaceAll(key, "_", "")
+}
+
+// parseBool accepts the boolean spellings used in wgetrc files
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "on", "yes", "true", "1":
+		return true, nil
+	case "off", "no", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value '%s'", v)
+}
+
+// Usage writes the list of options to w
+func (p *Parser) Usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [OPTION]... [URL]...\n\n", p.name)
+
+	aliases := make(map[*option][]string)
+	for name, opt := range p.long {
+		if name != opt.long {
+			aliases[opt] = append(aliases[opt], "--"+name)
+		}
+	}
+
+	for _, opt := range p.options {
+		names := "    "
+		if opt.short != "" {
+			names = "-" + opt.short + ", "
+		}
+		names += "--" + opt.long
+		switch {
+		case opt.optional:
+			names += "[=VALUE]"
+		case opt.hasArg:
+			names += "=VALUE"
+		}
+		sort.Strings(aliases[opt])
+		usage := opt.usage
+		if len(aliases[opt]) > 0 {
+			usage += " (also " + strings.Join(aliases[opt], ", ") + ")"
+		}
+		fmt.Fprintf(w, "  %-32s %s\n", names, usage)
+	}
+}
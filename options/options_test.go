@@ -0,0 +1,181 @@
+package options
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testFlags is a parser with a few options of every kind
+type testFlags struct {
+	parser     *Parser
+	quiet      *bool
+	verbose    *bool
+	noVerbose  *bool
+	continued  *bool
+	output     *string
+	tries      *string
+	limitRate  *string
+	headers    *[]string
+	extract    *bool
+	extractDir *string
+}
+
+func newTestFlags() *testFlags {
+	p := NewParser("wget")
+	f := &testFlags{parser: p}
+	f.quiet = p.Bool("quiet", "q", "")
+	f.verbose = p.Bool("verbose", "v", "")
+	f.noVerbose = p.Bool("no-verbose", "nv", "")
+	f.continued = p.Bool("continue", "c", "")
+	f.output = p.String("output-document", "O", "", "")
+	f.tries = p.String("tries", "t", "20", "")
+	f.limitRate = p.String("limit-rate", "", "", "")
+	p.Alias("rate-limit", "limit-rate")
+	f.headers = p.StringList("header", "", "")
+	f.extract, f.extractDir = p.Optional("extract", "")
+	return f
+}
+
+// values is what a test expects after parsing
+type values struct {
+	positional []string
+	quiet      bool
+	verbose    bool
+	noVerbose  bool
+	continued  bool
+	output     string
+	tries      string
+	limitRate  string
+	headers    []string
+	extract    bool
+	extractDir string
+}
+
+func (f *testFlags) values(positional []string) values {
+	return values{
+		positional: positional,
+		quiet:      *f.quiet,
+		verbose:    *f.verbose,
+		noVerbose:  *f.noVerbose,
+		continued:  *f.continued,
+		output:     *f.output,
+		tries:      *f.tries,
+		limitRate:  *f.limitRate,
+		headers:    *f.headers,
+		extract:    *f.extract,
+		extractDir: *f.extractDir,
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want values
+	}{
+		{"positional only", "a b", values{positional: []string{"a", "b"}, tries: "20"}},
+		{"bundled switches", "-qc url", values{positional: []string{"url"}, quiet: true, continued: true, tries: "20"}},
+		{"bundled value", "-qO- url", values{positional: []string{"url"}, quiet: true, output: "-", tries: "20"}},
+		{"bundled value with letters", "-cOfile.txt", values{continued: true, output: "file.txt", tries: "20"}},
+		{"short value in next argument", "-O out url", values{positional: []string{"url"}, output: "out", tries: "20"}},
+		{"short value with =", "-t=3", values{tries: "3"}},
+		{"two-letter option", "-nv url", values{positional: []string{"url"}, noVerbose: true, tries: "20"}},
+		{"two-letter option first in a cluster", "-nvc", values{noVerbose: true, continued: true, tries: "20"}},
+		{"long =value", "--tries=5 url", values{positional: []string{"url"}, tries: "5"}},
+		{"long value in next argument", "--tries 5 url", values{positional: []string{"url"}, tries: "5"}},
+		{"long empty =value", "--output-document= url", values{positional: []string{"url"}, tries: "20"}},
+		{"value that looks like an option", "--output-document --quiet", values{output: "--quiet", tries: "20"}},
+		{"negated switch", "-q --no-quiet", values{tries: "20"}},
+		{"alias", "--rate-limit=300k", values{limitRate: "300k", tries: "20"}},
+		{"abbreviation", "--cont --tri=4 --outp=x", values{continued: true, tries: "4", output: "x"}},
+		{"abbreviation of an alias", "--rate=1m", values{limitRate: "1m", tries: "20"}},
+		{"abbreviation of a negated switch", "-q --no-q", values{tries: "20"}},
+		{"option named no- wins over a negation", "--no-verb", values{noVerbose: true, tries: "20"}},
+		{"repeated list", "--header=A:1 --header B:2", values{headers: []string{"A:1", "B:2"}, tries: "20"}},
+		{"list cleared", "--header=A:1 --header= --header=B:2", values{headers: []string{"B:2"}, tries: "20"}},
+		{"optional value given", "--extract=dir url", values{positional: []string{"url"}, extract: true, extractDir: "dir", tries: "20"}},
+		{"optional value left out", "--extract url", values{positional: []string{"url"}, extract: true, tries: "20"}},
+		{"options after positionals", "url -q", values{positional: []string{"url"}, quiet: true, tries: "20"}},
+		{"double dash", "-q -- -c --tries=1", values{positional: []string{"-c", "--tries=1"}, quiet: true, tries: "20"}},
+		{"lone dash is positional", "-", values{positional: []string{"-"}, tries: "20"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFlags()
+			positional, err := f.parser.Parse(strings.Fields(tt.args))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.values(positional); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) =\n%+v\nwant\n%+v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		args string
+		err  string
+	}{
+		{"--nope", "unrecognized option '--nope'"},
+		{"-x", "invalid option -- 'x'"},
+		{"-qx", "invalid option -- 'x'"},
+		{"--tries", "option '--tries' requires an argument"},
+		{"-t", "option requires an argument -- 't'"},
+		{"--quiet=off", "option '--quiet' doesn't allow an argument"},
+		{"--no-quiet=1", "option '--no-quiet' doesn't allow an argument"},
+		{"--no-", "option '--no-' is ambiguous; possibilities: '--no-continue' '--no-no-verbose' '--no-quiet' '--no-verbose'"},
+		{"--no-q=1", "option '--no-q' doesn't allow an argument"},
+		{"----", "unrecognized option '----'"},
+	}
+	for _, tt := range tests {
+		f := newTestFlags()
+		_, err := f.parser.Parse(strings.Fields(tt.args))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.args, err, tt.err)
+		}
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	f := newTestFlags()
+	if _, err := f.parser.Parse([]string{"-t", "3", "--header", "Cli: 1", "--no-verbose"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"tries = 10", "limit_rate = 300k", "Header = Rc: 1", "header =", "header = Rc: 2", "no_verbose = off", "Quiet = on"} {
+		key, value, err := ParseCommand(line)
+		if err == nil {
+			err = f.parser.SetDefault(key, value)
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+	}
+
+	want := values{tries: "3", limitRate: "300k", headers: []string{"Rc: 2", "Cli: 1"}, noVerbose: true, quiet: true}
+	if got := f.values(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("after SetDefault:\n%+v\nwant\n%+v", got, want)
+	}
+	if err := f.parser.SetDefault("nope", "1"); err == nil || err.Error() != "unknown command 'nope'" {
+		t.Errorf("unknown key error = %v", err)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	f := newTestFlags()
+	if err := f.parser.Execute("limit_rate = 1m"); err != nil {
+		t.Fatal(err)
+	}
+	// -e counts as the command line, so configuration files don't override it
+	if err := f.parser.SetDefault("limit-rate", "2m"); err != nil {
+		t.Fatal(err)
+	}
+	if *f.limitRate != "1m" {
+		t.Errorf("limit rate %q, want 1m", *f.limitRate)
+	}
+	if err := f.parser.Execute("limit_rate"); err == nil {
+		t.Error("expected an error for a command without '='")
+	}
+}
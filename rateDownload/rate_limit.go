@@ -3,78 +3,82 @@ package rateDownload
 import (
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"strings"
 	"time"
+	"wget/exitStatus"
 )
 
-// Start handles downloading a file with rate limiting
-func Start(url string, rateLimit string) error {
-	// Parse the rate limit (e.g., "300k", "2M")
-	parsedRate, err := parseRateLimit(rateLimit)
-	if err != nil {
-		return err
-	}
-
-	// Make a GET request
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to download file: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Get file name from URL
-	fileName := getFileNameFromURL(url)
-	file, err := os.Create(fileName)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %v", err)
-	}
-	defer file.Close()
+// wireCounter is implemented by bodies decompressed while they are read.
+// The rate limit and progress then count the bytes received, which is what
+// size (the Content-Length) refers to.
+type wireCounter interface {
+	WireBytes() int64
+}
 
+// Copy writes src to dst, sleeping as needed to stay under rateLimit bytes
+// per second (0 means unlimited). size is the expected length, or -1 if
+// unknown. When showProgress is set a progress bar is drawn on stderr.
+func Copy(dst io.Writer, src io.Reader, size int64, rateLimit int64, showProgress bool) (int64, error) {
 	// Buffered read for controlled speed
-	buffer := make([]byte, 4096) // Read in chunks
-	var totalBytesDownloaded int64
+	buffer := make([]byte, 32*1024) // Read in chunks
+	if rateLimit > 0 && rateLimit < int64(len(buffer)) {
+		// Smaller chunks keep slow rates smooth
+		buffer = buffer[:4096]
+	}
+	var totalBytesDownloaded, received int64
 	startTime := time.Now()
+	wire, compressed := src.(wireCounter)
 
 	// Download loop with rate limit enforcement
 	for {
 		// Read from response body
-		n, err := resp.Body.Read(buffer)
-		if err != nil && err != io.EOF {
-			return fmt.Errorf("failed to read file: %v", err)
+		n, err := src.Read(buffer)
+		if n > 0 {
+			// Write to file
+			if _, werr := dst.Write(buffer[:n]); werr != nil {
+				return totalBytesDownloaded, exitStatus.Wrap(exitStatus.FileIO, fmt.Errorf("failed to write data to file: %v", werr))
+			}
+
+			// Update total downloaded
+			totalBytesDownloaded += int64(n)
+			newBytes := int64(n)
+			if compressed {
+				newBytes = wire.WireBytes() - received
+			}
+			received += newBytes
+
+			// Throttle speed
+			if rateLimit > 0 {
+				throttleDownload(int(newBytes), rateLimit)
+			}
+
+			// Display progress
+			if showProgress {
+				displayProgress(received, size, startTime)
+			}
 		}
-		if n == 0 {
+		if err == io.EOF {
 			break
 		}
-
-		// Write to file
-		_, err = file.Write(buffer[:n])
 		if err != nil {
-			return fmt.Errorf("failed to write data to file: %v", err)
+			// Timeouts and bad data keep their own status; anything else is a network failure
+			code := exitStatus.Of(err)
+			if code == exitStatus.Generic {
+				code = exitStatus.Network
+			}
+			return totalBytesDownloaded, exitStatus.Wrap(code, fmt.Errorf("failed to read file: %w", err))
 		}
-
-		// Update total downloaded
-		totalBytesDownloaded += int64(n)
-
-		// Throttle speed
-		throttleDownload(n, parsedRate)
-
-		// Display progress
-		displayProgress(totalBytesDownloaded, resp.ContentLength, startTime, parsedRate)
 	}
-	// Calculate total time taken
-	totalTime := time.Since(startTime).Seconds()
-
-	// Format total time to two decimal places
-	fmt.Printf("\nTime taken: %.2f seconds\n", totalTime)
-	fmt.Println("Download complete.")
+	if showProgress {
+		fmt.Fprintln(os.Stderr)
+	}
 
-	return nil
+	return totalBytesDownloaded, nil
 }
 
-// parseRateLimit converts rate strings (e.g., "300k", "2M") into bytes per second
-func parseRateLimit(rateLimit string) (int64, error) {
+// ParseRateLimit converts rate strings (e.g., "300k", "2M") into bytes per second
+func ParseRateLimit(rateLimit string) (int64, error) {
 	var bytesPerSecond int64
 	if strings.HasSuffix(rateLimit, "k") {
 		bytesPerSecond = parseRateUnit(rateLimit, 1024)
@@ -83,6 +87,9 @@ func parseRateLimit(rateLimit string) (int64, error) {
 	} else {
 		return 0, fmt.Errorf("unsupported rate limit unit: %s", rateLimit)
 	}
+	if bytesPerSecond <= 0 {
+		return 0, fmt.Errorf("invalid rate limit: %s", rateLimit)
+	}
 	return bytesPerSecond, nil
 }
 
@@ -98,15 +105,12 @@ func parseRateUnit(rateLimit string, unit int64) int64 {
 // throttleDownload sleeps to maintain the rate limit
 func throttleDownload(bytesDownloaded int, rateLimit int64) {
 	// Time required for the downloaded bytes to match rate limit
-	timeToSleep := float64(bytesDownloaded) / float64(rateLimit) // Seconds
+	timeToSleep := float64(bytesDownloaded) / float64(rateLimit)  // Seconds
 	time.Sleep(time.Duration(timeToSleep * float64(time.Second))) // Convert to time.Duration
 }
 
-// displayProgress shows download status and speed
-func displayProgress(totalBytes int64, totalSize int64, startTime time.Time, rateLimit int64) {
-	// Calculate percentage
-	percentage := float64(totalBytes) / float64(totalSize) * 100
-
+// displayProgress shows download status and speed on stderr
+func displayProgress(totalBytes int64, totalSize int64, startTime time.Time) {
 	// Calculate elapsed time
 	elapsedTime := time.Since(startTime).Seconds()
 
@@ -124,16 +128,25 @@ func displayProgress(totalBytes int64, totalSize int64, startTime time.Time, rat
 		speedDisplay = fmt.Sprintf("%.2f KB/s", speed/1024)
 	}
 
+	// Without a Content-Length only the byte count can be shown
+	if totalSize <= 0 {
+		fmt.Fprintf(os.Stderr, "\r%d bytes (Speed: %s)", totalBytes, speedDisplay)
+		return
+	}
+
+	// Calculate percentage
+	percentage := float64(totalBytes) / float64(totalSize) * 100
+	if percentage > 100 {
+		percentage = 100
+	}
+
 	// Estimate remaining time
-	remainingTime := time.Duration((float64(totalSize)-float64(totalBytes))/speed) * time.Second
+	var remainingTime time.Duration
+	if speed > 0 {
+		remainingTime = time.Duration((float64(totalSize)-float64(totalBytes))/speed) * time.Second
+	}
 
 	// Print progress
-	fmt.Printf("\r[%-50s] %.2f%% (Speed: %s, ETA: %v)",
+	fmt.Fprintf(os.Stderr, "\r[%-50s] %.2f%% (Speed: %s, ETA: %v)",
 		strings.Repeat("=", int(percentage/2)), percentage, speedDisplay, remainingTime)
 }
-
-// getFileNameFromURL extracts the filename from the URL
-func getFileNameFromURL(url string) string {
-	parts := strings.Split(url, "/")
-	return parts[len(parts)-1]
-}
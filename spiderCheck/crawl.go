@@ -0,0 +1,125 @@
+package spiderCheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"wget/downloader"
+	"wget/exitStatus"
+	"wget/job"
+)
+
+// maxPageSize is the most of an HTML page read when looking for links
+const maxPageSize = 10 << 20
+
+// Crawl walks the site at siteURL without saving anything (--spider with
+// --mirror). Every page, resource and outside link is checked; pages on
+// the site's host are followed. Broken links are listed at the end with
+// the pages that reference them.
+func Crawl(siteURL string, cfg *job.Config) (string, error) {
+	logger := cfg.Logger
+	startTime := time.Now()
+	site, err := url.Parse(siteURL)
+	if err != nil {
+		logger.Error("Invalid URL", "url", siteURL, "err", err)
+		return "", err
+	}
+	logger.Info("Spider started", "url", siteURL)
+
+	// referrers records the pages linking to each URL, in the order found
+	referrers := map[string][]string{siteURL: nil}
+	queue := []string{siteURL}
+	type broken struct {
+		url string
+		err error
+	}
+	var failures []broken
+	status, checked := exitStatus.Success, 0
+
+	for len(queue) > 0 {
+		link := queue[0]
+		queue = queue[1:]
+		checked++
+
+		var info Info
+		err := cfg.Retry(link, func(ctx context.Context) error {
+			var err error
+			info, err = check(ctx, link, cfg)
+			return err
+		})
+		if err != nil {
+			// Reported once at the end, with the pages that link to it
+			failures = append(failures, broken{link, err})
+			status = exitStatus.Combine(status, exitStatus.Of(err))
+			continue
+		}
+		logger.Info("Link OK", "url", link, "status", info.Status, "type", info.Type)
+
+		// Only pages of the site itself are searched for more links
+		target, err := url.Parse(link)
+		if err != nil || !strings.EqualFold(target.Host, site.Host) || !strings.Contains(info.Type, "text/html") {
+			continue
+		}
+		var page []byte
+		err = cfg.Retry(link, func(ctx context.Context) error {
+			var err error
+			page, err = fetchPage(ctx, link, cfg)
+			return err
+		})
+		if err != nil {
+			logger.Warn("Cannot read page", "url", link, "err", err)
+			continue
+		}
+
+		for _, found := range downloader.PageLinks(string(page), link) {
+			if !strings.HasPrefix(found, "http://") && !strings.HasPrefix(found, "https://") {
+				continue
+			}
+			if reason := downloader.Skipped(found, cfg); reason != "" {
+				logger.Debug(reason, "url", found)
+				continue
+			}
+			refs, seen := referrers[found]
+			if !slices.Contains(refs, link) {
+				referrers[found] = append(refs, link)
+			}
+			if !seen {
+				queue = append(queue, found)
+			}
+		}
+	}
+
+	// The broken-link report
+	logger.Info("Spider finished", "checked", checked, "broken", len(failures),
+		"seconds", fmt.Sprintf("%.2f", time.Since(startTime).Seconds()))
+	for _, failure := range failures {
+		referencedBy := strings.Join(referrers[failure.url], ", ")
+		if referencedBy == "" {
+			referencedBy = "(start URL)"
+		}
+		logger.Error("Broken link", "url", failure.url, "err", failure.err, "referenced_by", referencedBy)
+	}
+	if len(failures) > 0 {
+		return "", exitStatus.Wrap(status, fmt.Errorf("found %d broken links", len(failures)))
+	}
+	logger.Info("Found no broken links")
+	return "", nil
+}
+
+// fetchPage reads an HTML page to find its links
+func fetchPage(ctx context.Context, pageURL string, cfg *job.Config) ([]byte, error) {
+	resp, err := cfg.Client.GetContext(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, exitStatus.Wrap(exitStatus.ForHTTPStatus(resp.StatusCode), fmt.Errorf("server responded with %s", resp.Status))
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+}
@@ -0,0 +1,98 @@
+package spiderCheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"wget/exitStatus"
+	"wget/httpClient"
+	"wget/job"
+)
+
+// Info is what a check learns about a remote file
+type Info struct {
+	Status       string
+	Size         int64
+	Type         string
+	LastModified string
+}
+
+// Start checks that url exists without downloading it (--spider) and
+// reports its status, size, type and modification time. Nothing is saved,
+// so the returned path is always empty.
+func Start(url string, cfg *job.Config) (string, error) {
+	logger := cfg.Logger
+	var info Info
+	err := cfg.Retry(url, func(ctx context.Context) error {
+		var err error
+		info, err = check(ctx, url, cfg)
+		return err
+	})
+	if err != nil {
+		logger.Error("Remote file does not exist or is unreachable", "url", url, "err", err)
+		return "", err
+	}
+
+	size := "unknown"
+	if info.Size >= 0 {
+		size = strconv.FormatInt(info.Size, 10)
+	}
+	logger.Info("Remote file exists", "url", url, "status", info.Status, "size", size,
+		"type", info.Type, "last_modified", info.LastModified)
+	return "", nil
+}
+
+// check asks for the headers of url with HEAD. Servers that don't support
+// HEAD are asked for the first byte with a ranged GET instead.
+func check(ctx context.Context, url string, cfg *job.Config) (Info, error) {
+	resp, err := send(ctx, http.MethodHead, url, cfg)
+	if err != nil {
+		return Info{}, err
+	}
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		cfg.Logger.Debug("HEAD not supported, trying a ranged GET", "url", url, "status", resp.Status)
+		if resp, err = send(ctx, http.MethodGet, url, cfg); err != nil {
+			return Info{}, err
+		}
+	}
+
+	info := Info{
+		Status:       resp.Status,
+		Size:         resp.ContentLength,
+		Type:         resp.Header.Get("Content-Type"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		// Content-Range: bytes 0-0/12345
+		info.Size = -1
+		if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+			if n, err := strconv.ParseInt(total, 10, 64); err == nil {
+				info.Size = n
+			}
+		}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return info, exitStatus.Wrap(exitStatus.ForHTTPStatus(resp.StatusCode), fmt.Errorf("server responded with %s", resp.Status))
+	}
+	return info, nil
+}
+
+// send makes a HEAD request, or a GET for the first byte only. The body is
+// closed before returning.
+func send(ctx context.Context, method, url string, cfg *job.Config) (*http.Response, error) {
+	req, err := httpClient.NewRequest(method, url, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	resp, err := cfg.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
@@ -0,0 +1,167 @@
+package spiderCheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"wget/exitStatus"
+	"wget/httpClient"
+	"wget/job"
+)
+
+// newTestConfig returns a run configuration whose log goes to the returned
+// buffer as JSON lines
+func newTestConfig(t *testing.T) (*job.Config, *bytes.Buffer) {
+	t.Helper()
+	client, err := httpClient.New(httpClient.Options{
+		NetrcFile: filepath.Join(t.TempDir(), "netrc"),
+		NoHSTS:    true,
+		NoCookies: true,
+		NoProxy:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return &job.Config{Client: client, Logger: logger, Tries: 1}, &log
+}
+
+// logRecords returns the log records with the given message
+func logRecords(t *testing.T, log *bytes.Buffer, msg string) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range bytes.Split(log.Bytes(), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		if record["msg"] == msg {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+		case r.Method == http.MethodHead && (r.URL.Path == "/no-head" || r.URL.Path == "/no-head-no-range"):
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.Method == http.MethodHead && r.URL.Path == "/no-head-501":
+			w.WriteHeader(http.StatusNotImplemented)
+		case r.Method == http.MethodGet && r.Header.Get("Range") != "bytes=0-0":
+			t.Errorf("GET %s without the Range header", r.URL.Path)
+		case r.Method == http.MethodGet && r.URL.Path == "/no-head-no-range":
+			// The Range header is ignored and the whole file sent
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("whole body"))
+		case r.Method == http.MethodGet:
+			w.Header().Set("Content-Type", "application/zip")
+			w.Header().Set("Content-Range", "bytes 0-0/12345")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("P"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Length", "42")
+			w.Header().Set("Last-Modified", "Mon, 19 Oct 2026 10:00:00 GMT")
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want Info
+		code int
+	}{
+		{"/file", Info{Status: "200 OK", Size: 42, Type: "text/html", LastModified: "Mon, 19 Oct 2026 10:00:00 GMT"}, exitStatus.Success},
+		{"/no-head", Info{Status: "206 Partial Content", Size: 12345, Type: "application/zip"}, exitStatus.Success},
+		{"/no-head-501", Info{Status: "206 Partial Content", Size: 12345, Type: "application/zip"}, exitStatus.Success},
+		{"/no-head-no-range", Info{Status: "200 OK", Size: 10, Type: "text/plain"}, exitStatus.Success},
+		{"/missing", Info{Status: "404 Not Found", Size: 19, Type: "text/plain; charset=utf-8"}, exitStatus.ServerError},
+	}
+	cfg, _ := newTestConfig(t)
+	for _, tt := range tests {
+		info, err := check(context.Background(), server.URL+tt.path, cfg)
+		if code := exitStatus.Of(err); code != tt.code {
+			t.Errorf("%s: exit status %d (%v), want %d", tt.path, code, err, tt.code)
+		}
+		if info != tt.want {
+			t.Errorf("%s: %+v, want %+v", tt.path, info, tt.want)
+		}
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	outside := httptest.NewServer(http.NotFoundHandler())
+	defer outside.Close()
+
+	pages := map[string]string{
+		"/":           `<a href="/ok.txt">ok</a> <a href="/missing">a</a> <a href="page2.html">2</a> <img src="/img.png">`,
+		"/page2.html": fmt.Sprintf(`<a href="/missing">again</a> <a href="%s/gone">out</a> <a href="/">home</a>`, outside.URL),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if page, ok := pages[r.URL.Path]; ok {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(page))
+			return
+		}
+		switch r.URL.Path {
+		case "/ok.txt", "/img.png":
+			w.Write([]byte("fine"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg, log := newTestConfig(t)
+	_, err := Crawl(server.URL+"/", cfg)
+	if code := exitStatus.Of(err); code != exitStatus.ServerError {
+		t.Fatalf("exit status %d (%v), want %d", code, err, exitStatus.ServerError)
+	}
+
+	// Each broken link is reported once, with every page that links to it
+	want := map[string]string{
+		server.URL + "/missing": server.URL + "/, " + server.URL + "/page2.html",
+		outside.URL + "/gone":   server.URL + "/page2.html",
+	}
+	records := logRecords(t, log, "Broken link")
+	if len(records) != len(want) {
+		t.Fatalf("%d broken link records, want %d:\n%s", len(records), len(want), log)
+	}
+	for _, record := range records {
+		url, _ := record["url"].(string)
+		if record["level"] != "ERROR" || record["referenced_by"] != want[url] {
+			t.Errorf("record %v, want referenced_by %q at level ERROR", record, want[url])
+		}
+	}
+	if ok := len(logRecords(t, log, "Link OK")); ok != 4 {
+		t.Errorf("%d links OK, want 4", ok)
+	}
+}
+
+func TestCrawlBrokenStart(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	cfg, log := newTestConfig(t)
+	if _, err := Crawl(server.URL+"/", cfg); exitStatus.Of(err) != exitStatus.ServerError {
+		t.Fatalf("error %v, want a server error", err)
+	}
+	records := logRecords(t, log, "Broken link")
+	if len(records) != 1 || records[0]["referenced_by"] != "(start URL)" {
+		t.Errorf("records %v", records)
+	}
+}